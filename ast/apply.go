@@ -0,0 +1,165 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast
+
+type (
+	Cursor struct {
+		parent   ParentNode
+		index    int
+		node     Node
+		skip     int
+		deleted  bool
+		unwrap   bool
+		promoted int
+		visited  bool
+	}
+	ApplyFunc func(cursor *Cursor) bool
+	applier   struct {
+		pre  ApplyFunc
+		post ApplyFunc
+		root Node
+	}
+)
+
+//Apply traverses tree like Walk and lets pre and post modify it through cursor.
+//Children are skipped and post is not called when pre returns false, traversal stops when post returns false.
+//Nodes inserted by cursor are not traversed, replacement node and unwrapped children are. Apply returns possibly replaced root.
+func Apply(root Node, pre, post ApplyFunc) Node {
+	a := &applier{pre: pre, post: post, root: root}
+	if root != nil {
+		a.apply(&Cursor{node: root})
+	}
+	return a.root
+}
+
+//Node returns current node
+func (c *Cursor) Node() Node {
+	return c.node
+}
+
+//Parent returns parent of current node, nil for root
+func (c *Cursor) Parent() ParentNode {
+	return c.parent
+}
+
+//Index returns index of current node in children of parent
+func (c *Cursor) Index() int {
+	return c.index
+}
+
+//Replace replaces current node
+func (c *Cursor) Replace(node Node) {
+	if c.parent != nil {
+		c.parent.ReplaceNode(c.index, node)
+	}
+	c.node = node
+}
+
+//Delete deletes current node, its children are not traversed
+func (c *Cursor) Delete() {
+	c.mustHaveParent()
+	c.parent.DeleteNode(c.index)
+	c.deleted = true
+}
+
+//InsertBefore inserts nodes before current node
+func (c *Cursor) InsertBefore(nodes ...Node) {
+	c.mustHaveParent()
+	c.parent.InsertNode(c.index, nodes...)
+	c.index += len(nodes)
+}
+
+//InsertAfter inserts nodes after current node
+func (c *Cursor) InsertAfter(nodes ...Node) {
+	c.mustHaveParent()
+	c.parent.InsertNode(c.index+1, nodes...)
+	c.skip += len(nodes)
+}
+
+//Unwrap replaces current node with its children, they are traversed next when called from pre
+func (c *Cursor) Unwrap() {
+	c.mustHaveParent()
+	var children []Node
+	if parent, ok := c.node.(ParentNode); ok {
+		children = append(children, parent.GetChildren()...)
+	}
+	c.parent.ReplaceNode(c.index, children...)
+	c.unwrap = true
+	c.promoted = len(children)
+}
+
+func (c *Cursor) mustHaveParent() {
+	if c.parent == nil {
+		panic("yaastr: root node can only be replaced")
+	}
+}
+
+//apply returns false if traversal is stopped
+func (a *applier) apply(cursor *Cursor) bool {
+	if a.pre != nil && !a.pre(cursor) {
+		a.replaceRoot(cursor)
+		return true
+	}
+	a.replaceRoot(cursor)
+	if cursor.deleted || cursor.unwrap {
+		return true
+	}
+	if parent, ok := cursor.node.(ParentNode); ok {
+		for index := 0; index < len(parent.GetChildren()); {
+			child := &Cursor{parent: parent, index: index, node: parent.GetChildren()[index]}
+			if !a.apply(child) {
+				return false
+			}
+			index = child.next()
+		}
+	}
+	cursor.visited = true
+	if a.post != nil && !a.post(cursor) {
+		a.replaceRoot(cursor)
+		return false
+	}
+	a.replaceRoot(cursor)
+	return true
+}
+
+func (a *applier) replaceRoot(cursor *Cursor) {
+	if cursor.parent == nil {
+		a.root = cursor.node
+	}
+}
+
+//next returns index of next sibling to traverse
+func (c *Cursor) next() int {
+	switch {
+	case c.deleted:
+		return c.index + c.skip
+	case c.unwrap && c.visited:
+		return c.index + c.promoted + c.skip
+	case c.unwrap:
+		return c.index + c.skip
+	}
+	return c.index + 1 + c.skip
+}
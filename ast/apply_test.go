@@ -0,0 +1,161 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast_test
+
+import (
+	"github.com/biodebox/yaastr/ast"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func names(node ast.Node) []string {
+	var result []string
+	ast.Inspect(node, func(node ast.Node) bool {
+		result = append(result, name(node))
+		return true
+	})
+	return result
+}
+
+func TestApply(t *testing.T) {
+	t.Run(`replace`, func(t *testing.T) {
+		root := tree()
+		var visited []string
+		ast.Apply(root, func(cursor *ast.Cursor) bool {
+			visited = append(visited, name(cursor.Node()))
+			if name(cursor.Node()) == `a` {
+				cursor.Replace(ast.NewContainer(ast.NewText([]byte(`x`)...)))
+			}
+			return true
+		}, nil)
+		assert.Equal(t, []string{`container(3)`, `a`, `x`, `container(1)`, `b`, `c`}, visited)
+		assert.Equal(t, []string{`container(3)`, `container(1)`, `x`, `container(1)`, `b`, `c`}, names(root))
+		assert.Equal(t, root, root.Children[0].GetParent())
+	})
+	t.Run(`delete`, func(t *testing.T) {
+		root := tree()
+		var visited []string
+		ast.Apply(root, func(cursor *ast.Cursor) bool {
+			visited = append(visited, name(cursor.Node()))
+			if name(cursor.Node()) == `container(1)` || name(cursor.Node()) == `a` {
+				cursor.Delete()
+			}
+			return true
+		}, nil)
+		assert.Equal(t, []string{`container(3)`, `a`, `container(1)`, `c`}, visited)
+		assert.Equal(t, []string{`container(1)`, `c`}, names(root))
+	})
+	t.Run(`delete in post`, func(t *testing.T) {
+		root := tree()
+		var visited []string
+		ast.Apply(root, nil, func(cursor *ast.Cursor) bool {
+			visited = append(visited, name(cursor.Node()))
+			if _, ok := cursor.Node().(*ast.Text); ok && cursor.Parent() == root {
+				cursor.Delete()
+			}
+			return true
+		})
+		assert.Equal(t, []string{`a`, `b`, `container(1)`, `c`, `container(1)`}, visited)
+		assert.Equal(t, []string{`container(1)`, `container(1)`, `b`}, names(root))
+	})
+	t.Run(`insert`, func(t *testing.T) {
+		root := tree()
+		var visited []string
+		ast.Apply(root, func(cursor *ast.Cursor) bool {
+			visited = append(visited, name(cursor.Node()))
+			if name(cursor.Node()) == `b` {
+				cursor.InsertBefore(ast.NewText([]byte(`before`)...))
+				cursor.InsertAfter(ast.NewText([]byte(`after`)...))
+				assert.Equal(t, 1, cursor.Index())
+			}
+			return true
+		}, nil)
+		assert.Equal(t, []string{`container(3)`, `a`, `container(1)`, `b`, `c`}, visited)
+		assert.Equal(t, []string{`container(3)`, `a`, `container(3)`, `before`, `b`, `after`, `c`}, names(root))
+		for _, child := range root.Children[1].(*ast.Container).Children {
+			assert.Equal(t, root.Children[1], child.GetParent())
+		}
+	})
+	t.Run(`unwrap`, func(t *testing.T) {
+		root := tree()
+		var visited []string
+		ast.Apply(root, func(cursor *ast.Cursor) bool {
+			visited = append(visited, name(cursor.Node()))
+			if name(cursor.Node()) == `container(1)` {
+				cursor.Unwrap()
+			}
+			return true
+		}, nil)
+		assert.Equal(t, []string{`container(3)`, `a`, `container(1)`, `b`, `c`}, visited)
+		assert.Equal(t, []string{`container(3)`, `a`, `b`, `c`}, names(root))
+		assert.Equal(t, root, root.Children[1].GetParent())
+	})
+	t.Run(`unwrap in post`, func(t *testing.T) {
+		root := tree()
+		var visited []string
+		ast.Apply(root, nil, func(cursor *ast.Cursor) bool {
+			visited = append(visited, name(cursor.Node()))
+			if name(cursor.Node()) == `container(1)` {
+				cursor.Unwrap()
+			}
+			return true
+		})
+		assert.Equal(t, []string{`a`, `b`, `container(1)`, `c`, `container(3)`}, visited)
+		assert.Equal(t, []string{`container(3)`, `a`, `b`, `c`}, names(root))
+	})
+	t.Run(`skip children`, func(t *testing.T) {
+		var visited []string
+		ast.Apply(tree(), func(cursor *ast.Cursor) bool {
+			visited = append(visited, name(cursor.Node()))
+			return name(cursor.Node()) != `container(1)`
+		}, nil)
+		assert.Equal(t, []string{`container(3)`, `a`, `container(1)`, `c`}, visited)
+	})
+	t.Run(`stop`, func(t *testing.T) {
+		var visited []string
+		ast.Apply(tree(), nil, func(cursor *ast.Cursor) bool {
+			visited = append(visited, name(cursor.Node()))
+			return name(cursor.Node()) != `b`
+		})
+		assert.Equal(t, []string{`a`, `b`}, visited)
+	})
+	t.Run(`root`, func(t *testing.T) {
+		text := ast.NewText([]byte(`root`)...)
+		root := ast.Apply(tree(), func(cursor *ast.Cursor) bool {
+			if cursor.Parent() == nil {
+				cursor.Replace(text)
+			}
+			return true
+		}, nil)
+		assert.Equal(t, text, root)
+		assert.Panics(t, func() {
+			ast.Apply(tree(), func(cursor *ast.Cursor) bool {
+				cursor.Delete()
+				return true
+			}, nil)
+		})
+	})
+}
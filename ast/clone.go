@@ -0,0 +1,100 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast
+
+import "fmt"
+
+type (
+	Cloner interface {
+		//CloneNode returns copy of node without parent and children, span is copied by Clone
+		CloneNode() Node
+	}
+)
+
+//Clone deep copies node with its children and content, copy has no parent.
+//Node types other than Text, Error, Container and Document must implement Cloner.
+func Clone(node Node) Node {
+	if node == nil {
+		return nil
+	}
+	clone := cloneNode(node)
+	if span, ok := SpanOf(node); ok {
+		if spanned, ok := clone.(Spanned); ok {
+			spanned.SetSpan(span)
+		}
+	}
+	if delimited, ok := node.(Delimited); ok {
+		if target, ok := clone.(Delimited); ok {
+			opening, closing := delimited.GetDelimiters()
+			target.SetDelimiters(copyBytes(opening), copyBytes(closing))
+		}
+	}
+	if parent, ok := node.(ParentNode); ok {
+		target := clone.(ParentNode)
+		for _, child := range parent.GetChildren() {
+			target.AppendNode(Clone(child))
+		}
+	}
+	return clone
+}
+
+//CanClone reports whether Clone supports node and all its descendants
+func CanClone(node Node) bool {
+	result := true
+	Inspect(node, func(node Node) bool {
+		switch node.(type) {
+		case Cloner, *Text, *Error, *Container, *Document:
+		default:
+			result = false
+		}
+		return result
+	})
+	return result
+}
+
+func cloneNode(node Node) Node {
+	if cloner, ok := node.(Cloner); ok {
+		return cloner.CloneNode()
+	}
+	switch n := node.(type) {
+	case *Text:
+		return &Text{Content: copyBytes(n.Content)}
+	case *Error:
+		return &Error{Content: copyBytes(n.Content), Err: n.Err}
+	case *Container:
+		return &Container{}
+	case *Document:
+		return &Document{}
+	}
+	panic(fmt.Sprintf("yaastr: %T does not implement ast.Cloner", node))
+}
+
+func copyBytes(data []byte) []byte {
+	if data == nil {
+		return nil
+	}
+	return append(make([]byte, 0, len(data)), data...)
+}
@@ -0,0 +1,113 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast_test
+
+import (
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type emphasis struct {
+	*ast.Container
+	strong bool
+}
+
+func (e *emphasis) CloneNode() ast.Node {
+	return &emphasis{Container: ast.NewContainer(), strong: e.strong}
+}
+
+func (e *emphasis) EqualNode(other ast.Node) bool {
+	return e.strong == other.(*emphasis).strong
+}
+
+func TestClone(t *testing.T) {
+	t.Run(`nil`, func(t *testing.T) {
+		assert.Nil(t, ast.Clone(nil))
+	})
+	t.Run(`text`, func(t *testing.T) {
+		text := ast.NewText([]byte(`text`)...)
+		text.SetSpan(ast.Span{Start: 1, End: 5})
+		clone := ast.Clone(text).(*ast.Text)
+		if !assert.Equal(t, text, clone) {
+			return
+		}
+		clone.Content[0] = 'n'
+		assert.Equal(t, []byte(`text`), text.Content)
+	})
+	t.Run(`error`, func(t *testing.T) {
+		node := ast.NewError(errors.New(`broken`), []byte(`'a`)...)
+		node.SetSpan(ast.Span{Start: 2, End: 4})
+		clone := ast.Clone(node).(*ast.Error)
+		if !assert.Equal(t, node, clone) {
+			return
+		}
+		clone.Content[0] = 'n'
+		assert.Equal(t, []byte(`'a`), node.Content)
+	})
+	t.Run(`deep`, func(t *testing.T) {
+		doc := &ast.Document{}
+		doc.AppendNode(tree(), ast.NewText([]byte(`d`)...))
+		clone := ast.Clone(doc).(*ast.Document)
+		if !assert.Equal(t, doc, clone) {
+			return
+		}
+		assert.False(t, doc.Children[0] == clone.Children[0])
+		assert.Equal(t, &clone.Container, clone.Children[0].GetParent())
+		inner := clone.Children[0].(*ast.Container).Children[1].(*ast.Container)
+		assert.Equal(t, inner, inner.Children[0].GetParent())
+	})
+	t.Run(`detached`, func(t *testing.T) {
+		root := tree()
+		clone := ast.Clone(root.Children[1])
+		assert.Nil(t, clone.GetParent())
+		assert.Equal(t, root, root.Children[1].GetParent())
+	})
+	t.Run(`cloner`, func(t *testing.T) {
+		e := &emphasis{Container: ast.NewContainer(ast.NewText([]byte(`e`)...)), strong: true}
+		root := ast.NewContainer(e)
+		clone := ast.Clone(root).(*ast.Container)
+		if !assert.Equal(t, root, clone) {
+			return
+		}
+		cloned := clone.Children[0].(*emphasis)
+		assert.True(t, cloned.strong)
+		assert.Equal(t, cloned.Container, cloned.Children[0].GetParent())
+	})
+	t.Run(`unsupported`, func(t *testing.T) {
+		assert.Panics(t, func() {
+			ast.Clone(&struct{ ast.Child }{})
+		})
+	})
+}
+
+func TestCanClone(t *testing.T) {
+	plain := &struct{ *ast.Container }{Container: ast.NewContainer()}
+	assert.True(t, ast.CanClone(ast.NewContainer(ast.NewText(), &emphasis{Container: ast.NewContainer()}, ast.NewError(nil))))
+	assert.False(t, ast.CanClone(plain))
+	assert.False(t, ast.CanClone(ast.NewContainer(ast.NewText(), ast.NewContainer(plain))))
+}
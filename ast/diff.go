@@ -0,0 +1,154 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast
+
+import (
+	"fmt"
+	"reflect"
+)
+
+type (
+	ChangeKind int
+	Change     struct {
+		Kind ChangeKind
+		//Path is list of child indexes from root in old tree, last index of Inserted refers to new tree
+		Path []int
+		Old  Node
+		New  Node
+	}
+)
+
+const (
+	Inserted ChangeKind = iota
+	Deleted
+	Changed
+)
+
+//Diff returns changes turning tree a into tree b, children are aligned by longest common subsequence
+func Diff(a, b Node) []Change {
+	return diff(nil, a, b)
+}
+
+//String
+func (k ChangeKind) String() string {
+	switch k {
+	case Inserted:
+		return "inserted"
+	case Deleted:
+		return "deleted"
+	case Changed:
+		return "changed"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+//String
+func (c Change) String() string {
+	return fmt.Sprintf("%s %v", c.Kind, c.Path)
+}
+
+func diff(path []int, a, b Node) []Change {
+	if !equalNode(a, b) {
+		return []Change{{Kind: Changed, Path: path, Old: a, New: b}}
+	}
+	parentA, ok := a.(ParentNode)
+	if !ok {
+		return nil
+	}
+	childrenA, childrenB := parentA.GetChildren(), b.(ParentNode).GetChildren()
+	var changes []Change
+	i, j := 0, 0
+	for _, match := range lcs(childrenA, childrenB, Equal) {
+		changes = append(changes, diffGap(path, childrenA[i:match[0]], childrenB[j:match[1]], i, j)...)
+		i, j = match[0]+1, match[1]+1
+	}
+	return append(changes, diffGap(path, childrenA[i:], childrenB[j:], i, j)...)
+}
+
+//diffGap compares unmatched children starting at offsetA and offsetB, similar nodes are compared in place
+func diffGap(path []int, a, b []Node, offsetA, offsetB int) []Change {
+	var changes []Change
+	i, j := 0, 0
+	for _, match := range append(lcs(a, b, similar), [2]int{len(a), len(b)}) {
+		for ; i < match[0]; i++ {
+			changes = append(changes, Change{Kind: Deleted, Path: childPath(path, offsetA+i), Old: a[i]})
+		}
+		for ; j < match[1]; j++ {
+			changes = append(changes, Change{Kind: Inserted, Path: childPath(path, offsetB+j), New: b[j]})
+		}
+		if i < len(a) && j < len(b) {
+			changes = append(changes, diff(childPath(path, offsetA+i), a[i], b[j])...)
+			i, j = i+1, j+1
+		}
+	}
+	return changes
+}
+
+//lcs returns index pairs of matching children in longest common subsequence
+func lcs(a, b []Node, match func(a, b Node) bool) [][2]int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if match(a[i], b[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+	var matches [][2]int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case match(a[i], b[j]):
+			matches = append(matches, [2]int{i, j})
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return matches
+}
+
+//similar reports whether nodes can be compared in place, text and error nodes are similar regardless of content
+func similar(a, b Node) bool {
+	switch a.(type) {
+	case *Text, *Error:
+		return reflect.TypeOf(a) == reflect.TypeOf(b)
+	}
+	return equalNode(a, b)
+}
+
+func childPath(path []int, index int) []int {
+	return append(append(make([]int, 0, len(path)+1), path...), index)
+}
@@ -0,0 +1,93 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast
+
+import (
+	"bytes"
+	"reflect"
+)
+
+type (
+	Equaler interface {
+		//EqualNode compares own fields of nodes of the same type, children are compared by Equal
+		EqualNode(other Node) bool
+	}
+)
+
+//Equal compares type, content, delimiters and children of nodes, parent pointers and spans are ignored
+func Equal(a, b Node) bool {
+	if !equalNode(a, b) {
+		return false
+	}
+	parentA, ok := a.(ParentNode)
+	if !ok {
+		return true
+	}
+	childrenA, childrenB := parentA.GetChildren(), b.(ParentNode).GetChildren()
+	if len(childrenA) != len(childrenB) {
+		return false
+	}
+	for index := range childrenA {
+		if !Equal(childrenA[index], childrenB[index]) {
+			return false
+		}
+	}
+	return true
+}
+
+//equalNode compares nodes without children
+func equalNode(a, b Node) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	if equaler, ok := a.(Equaler); ok && !equaler.EqualNode(b) {
+		return false
+	}
+	if delimited, ok := a.(Delimited); ok {
+		openingA, closingA := delimited.GetDelimiters()
+		openingB, closingB := b.(Delimited).GetDelimiters()
+		if !bytes.Equal(openingA, openingB) || !bytes.Equal(closingA, closingB) {
+			return false
+		}
+	}
+	switch n := a.(type) {
+	case *Text:
+		return bytes.Equal(n.Content, b.(*Text).Content)
+	case *Error:
+		return bytes.Equal(n.Content, b.(*Error).Content) && errorMessage(n.Err) == errorMessage(b.(*Error).Err)
+	}
+	return true
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
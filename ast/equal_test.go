@@ -0,0 +1,161 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast_test
+
+import (
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	t.Run(`nil`, func(t *testing.T) {
+		assert.True(t, ast.Equal(nil, nil))
+		assert.False(t, ast.Equal(nil, tree()))
+		assert.False(t, ast.Equal(tree(), nil))
+	})
+	t.Run(`same structure`, func(t *testing.T) {
+		a, b := tree(), tree()
+		b.Children[0].(*ast.Text).SetSpan(ast.Span{Start: 1, End: 2})
+		assert.True(t, ast.Equal(a, b))
+	})
+	t.Run(`parent ignored`, func(t *testing.T) {
+		a := tree()
+		b := ast.NewContainer(a.Children...)
+		assert.True(t, ast.Equal(tree(), b))
+	})
+	t.Run(`content`, func(t *testing.T) {
+		b := tree()
+		b.Children[1].(*ast.Container).Children[0].(*ast.Text).Content = []byte(`x`)
+		assert.False(t, ast.Equal(tree(), b))
+	})
+	t.Run(`error`, func(t *testing.T) {
+		a := ast.NewContainer(ast.NewError(errors.New(`broken`), 'x'))
+		assert.True(t, ast.Equal(a, ast.NewContainer(ast.NewError(errors.New(`broken`), 'x'))))
+		assert.False(t, ast.Equal(a, ast.NewContainer(ast.NewError(errors.New(`other`), 'x'))))
+		assert.False(t, ast.Equal(a, ast.NewContainer(ast.NewError(nil, 'x'))))
+		assert.False(t, ast.Equal(a, ast.NewContainer(ast.NewError(errors.New(`broken`), 'y'))))
+	})
+	t.Run(`type`, func(t *testing.T) {
+		doc := &ast.Document{}
+		doc.AppendNode(tree().Children...)
+		assert.False(t, ast.Equal(tree(), doc))
+	})
+	t.Run(`children`, func(t *testing.T) {
+		b := tree()
+		b.DeleteNode(2)
+		assert.False(t, ast.Equal(tree(), b))
+	})
+	t.Run(`delimiters`, func(t *testing.T) {
+		a, b := ast.NewContainer(), ast.NewContainer()
+		a.SetDelimiters([]byte(`(`), []byte(`)`))
+		b.SetDelimiters([]byte(`[`), []byte(`)`))
+		assert.False(t, ast.Equal(a, b))
+		assert.NotEmpty(t, ast.Diff(a, b))
+		b.SetDelimiters([]byte(`(`), []byte(`)`))
+		assert.True(t, ast.Equal(a, b))
+		b.SetDelimiters([]byte(`(`), nil)
+		assert.False(t, ast.Equal(a, b))
+	})
+	t.Run(`equaler`, func(t *testing.T) {
+		a := &emphasis{Container: ast.NewContainer(), strong: true}
+		b := &emphasis{Container: ast.NewContainer(), strong: false}
+		assert.False(t, ast.Equal(a, b))
+		b.strong = true
+		assert.True(t, ast.Equal(a, b))
+	})
+	t.Run(`cycle`, func(t *testing.T) {
+		root := tree()
+		clone := ast.Clone(root)
+		assert.True(t, ast.Equal(root, clone))
+	})
+}
+
+func TestDiff(t *testing.T) {
+	t.Run(`equal`, func(t *testing.T) {
+		assert.Empty(t, ast.Diff(tree(), tree()))
+	})
+	t.Run(`root`, func(t *testing.T) {
+		text := ast.NewText([]byte(`text`)...)
+		assert.Equal(t, []ast.Change{{Kind: ast.Changed, Old: tree(), New: text}}, ast.Diff(tree(), text))
+	})
+	t.Run(`changed text`, func(t *testing.T) {
+		b := tree()
+		changed := b.Children[1].(*ast.Container).Children[0].(*ast.Text)
+		changed.Content = []byte(`x`)
+		changes := ast.Diff(tree(), b)
+		if !assert.Len(t, changes, 1) {
+			return
+		}
+		assert.Equal(t, ast.Changed, changes[0].Kind)
+		assert.Equal(t, []int{1, 0}, changes[0].Path)
+		assert.Equal(t, changed, changes[0].New)
+		assert.Equal(t, `changed [1 0]`, changes[0].String())
+	})
+	t.Run(`inserted`, func(t *testing.T) {
+		b := tree()
+		b.InsertNode(1, ast.NewText([]byte(`x`)...))
+		changes := ast.Diff(tree(), b)
+		if !assert.Len(t, changes, 1) {
+			return
+		}
+		assert.Equal(t, ast.Inserted, changes[0].Kind)
+		assert.Equal(t, []int{1}, changes[0].Path)
+	})
+	t.Run(`deleted`, func(t *testing.T) {
+		b := tree()
+		b.DeleteNode(1)
+		changes := ast.Diff(tree(), b)
+		if !assert.Len(t, changes, 1) {
+			return
+		}
+		assert.Equal(t, ast.Deleted, changes[0].Kind)
+		assert.Equal(t, []int{1}, changes[0].Path)
+		assert.Equal(t, `container(1)`, name(changes[0].Old))
+	})
+	t.Run(`replaced`, func(t *testing.T) {
+		b := tree()
+		b.ReplaceNode(1, &emphasis{Container: ast.NewContainer()})
+		changes := ast.Diff(tree(), b)
+		if !assert.Len(t, changes, 2) {
+			return
+		}
+		assert.Equal(t, []string{`deleted [1]`, `inserted [1]`}, []string{changes[0].String(), changes[1].String()})
+	})
+	t.Run(`nested`, func(t *testing.T) {
+		b := tree()
+		inner := b.Children[1].(*ast.Container)
+		inner.AppendNode(ast.NewText([]byte(`x`)...))
+		b.DeleteNode(0)
+		changes := ast.Diff(tree(), b)
+		var result []string
+		for _, change := range changes {
+			result = append(result, change.String())
+		}
+		assert.Equal(t, []string{`deleted [0]`, `inserted [1 1]`}, result)
+	})
+}
@@ -0,0 +1,252 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"unicode/utf8"
+)
+
+type (
+	JSONCodec struct {
+		//New creates empty node of registered type
+		New func() Node
+		//Encode returns attributes of node, nil attributes are omitted, optional
+		Encode func(node Node) (interface{}, error)
+		//Decode sets attributes on node created by New, optional
+		Decode func(node Node, attributes json.RawMessage) error
+	}
+	JSONRegistry struct {
+		mutex  sync.RWMutex
+		names  map[reflect.Type]string
+		codecs map[string]JSONCodec
+	}
+	jsonNode struct {
+		Type       string          `json:"type"`
+		Span       *Span           `json:"span,omitempty"`
+		Content    jsonBytes       `json:"content,omitempty"`
+		Opening    jsonBytes       `json:"opening,omitempty"`
+		Closing    jsonBytes       `json:"closing,omitempty"`
+		Attributes json.RawMessage `json:"attributes,omitempty"`
+		Children   []*jsonNode     `json:"children,omitempty"`
+	}
+	//jsonBytes is encoded as string when it is valid UTF-8 and as object with base64 field otherwise
+	jsonBytes    []byte
+	jsonRawBytes struct {
+		Base64 []byte `json:"base64"`
+	}
+)
+
+//DefaultJSONRegistry is used by MarshalJSON and UnmarshalJSON
+var DefaultJSONRegistry = NewJSONRegistry()
+
+//NewJSONRegistry creates registry with text, error, container and document types
+func NewJSONRegistry() *JSONRegistry {
+	r := &JSONRegistry{names: map[reflect.Type]string{}, codecs: map[string]JSONCodec{}}
+	r.Register("text", JSONCodec{New: func() Node { return &Text{} }})
+	r.Register("error", JSONCodec{New: func() Node { return &Error{} }, Encode: encodeError, Decode: decodeError})
+	r.Register("container", JSONCodec{New: func() Node { return &Container{} }})
+	r.Register("document", JSONCodec{New: func() Node { return &Document{} }})
+	return r
+}
+
+//RegisterJSON registers node type in DefaultJSONRegistry
+func RegisterJSON(name string, codec JSONCodec) {
+	DefaultJSONRegistry.Register(name, codec)
+}
+
+//MarshalJSON encodes tree with DefaultJSONRegistry
+func MarshalJSON(node Node) ([]byte, error) {
+	return DefaultJSONRegistry.Marshal(node)
+}
+
+//UnmarshalJSON decodes tree with DefaultJSONRegistry
+func UnmarshalJSON(data []byte) (Node, error) {
+	return DefaultJSONRegistry.Unmarshal(data)
+}
+
+//Register binds type discriminator name to type of node created by codec.New
+func (r *JSONRegistry) Register(name string, codec JSONCodec) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.names[reflect.TypeOf(codec.New())] = name
+	r.codecs[name] = codec
+}
+
+//Marshal encodes tree, parent pointers are omitted
+func (r *JSONRegistry) Marshal(node Node) ([]byte, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	encoded, err := r.encode(node)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encoded)
+}
+
+//Unmarshal decodes tree and restores parent pointers
+func (r *JSONRegistry) Unmarshal(data []byte) (Node, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var decoded jsonNode
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return r.decode(&decoded)
+}
+
+func (r *JSONRegistry) encode(node Node) (*jsonNode, error) {
+	name, ok := r.names[reflect.TypeOf(node)]
+	if !ok {
+		return nil, fmt.Errorf("yaastr: unregistered node type %T", node)
+	}
+	encoded := &jsonNode{Type: name}
+	if span, ok := SpanOf(node); ok {
+		encoded.Span = &span
+	}
+	switch n := node.(type) {
+	case *Text:
+		encoded.Content = n.Content
+	case *Error:
+		encoded.Content = n.Content
+	}
+	if delimited, ok := node.(Delimited); ok {
+		opening, closing := delimited.GetDelimiters()
+		encoded.Opening, encoded.Closing = opening, closing
+	}
+	if codec := r.codecs[name]; codec.Encode != nil {
+		attributes, err := codec.Encode(node)
+		if err != nil {
+			return nil, err
+		}
+		if attributes != nil {
+			if encoded.Attributes, err = json.Marshal(attributes); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if parent, ok := node.(ParentNode); ok {
+		for _, child := range parent.GetChildren() {
+			encodedChild, err := r.encode(child)
+			if err != nil {
+				return nil, err
+			}
+			encoded.Children = append(encoded.Children, encodedChild)
+		}
+	}
+	return encoded, nil
+}
+
+func (r *JSONRegistry) decode(encoded *jsonNode) (Node, error) {
+	codec, ok := r.codecs[encoded.Type]
+	if !ok {
+		return nil, fmt.Errorf("yaastr: unregistered node type %q", encoded.Type)
+	}
+	node := codec.New()
+	if spanned, ok := node.(Spanned); ok && encoded.Span != nil {
+		spanned.SetSpan(*encoded.Span)
+	}
+	if len(encoded.Content) > 0 {
+		switch n := node.(type) {
+		case *Text:
+			n.Content = encoded.Content
+		case *Error:
+			n.Content = encoded.Content
+		}
+	}
+	if delimited, ok := node.(Delimited); ok && (len(encoded.Opening) > 0 || len(encoded.Closing) > 0) {
+		delimited.SetDelimiters(encoded.Opening, encoded.Closing)
+	}
+	if codec.Decode != nil && encoded.Attributes != nil {
+		if err := codec.Decode(node, encoded.Attributes); err != nil {
+			return nil, err
+		}
+	}
+	if len(encoded.Children) == 0 {
+		return node, nil
+	}
+	parent, ok := node.(ParentNode)
+	if !ok {
+		return nil, fmt.Errorf("yaastr: node type %q can not have children", encoded.Type)
+	}
+	for _, encodedChild := range encoded.Children {
+		child, err := r.decode(encodedChild)
+		if err != nil {
+			return nil, err
+		}
+		parent.AppendNode(child)
+	}
+	return node, nil
+}
+
+//encodeError keeps only message of error
+func encodeError(node Node) (interface{}, error) {
+	if err := node.(*Error).Err; err != nil {
+		return map[string]string{"error": err.Error()}, nil
+	}
+	return nil, nil
+}
+
+func decodeError(node Node, attributes json.RawMessage) error {
+	var decoded map[string]string
+	if err := json.Unmarshal(attributes, &decoded); err != nil {
+		return err
+	}
+	if message, ok := decoded["error"]; ok {
+		node.(*Error).Err = errors.New(message)
+	}
+	return nil
+}
+
+//MarshalJSON
+func (b jsonBytes) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(jsonRawBytes{Base64: b})
+}
+
+//UnmarshalJSON
+func (b *jsonBytes) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		var raw jsonRawBytes
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+		*b = raw.Base64
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	*b = jsonBytes(text)
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func emphasisRegistry() *ast.JSONRegistry {
+	registry := ast.NewJSONRegistry()
+	registry.Register(`emphasis`, ast.JSONCodec{
+		New: func() ast.Node {
+			return &emphasis{Container: ast.NewContainer()}
+		},
+		Encode: func(node ast.Node) (interface{}, error) {
+			return map[string]bool{`strong`: node.(*emphasis).strong}, nil
+		},
+		Decode: func(node ast.Node, attributes json.RawMessage) error {
+			var decoded map[string]bool
+			if err := json.Unmarshal(attributes, &decoded); err != nil {
+				return err
+			}
+			node.(*emphasis).strong = decoded[`strong`]
+			return nil
+		},
+	})
+	return registry
+}
+
+func TestMarshalJSON(t *testing.T) {
+	t.Run(`format`, func(t *testing.T) {
+		doc := &ast.Document{}
+		text := ast.NewText([]byte(`a`)...)
+		text.SetSpan(ast.Span{Start: 0, End: 1})
+		doc.AppendNode(text, ast.NewContainer())
+		data, err := ast.MarshalJSON(doc)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.JSONEq(t, `{
+			"type": "document",
+			"span": {"start": 0, "end": 0},
+			"children": [
+				{"type": "text", "span": {"start": 0, "end": 1}, "content": "a"},
+				{"type": "container", "span": {"start": 0, "end": 0}}
+			]
+		}`, string(data))
+	})
+	t.Run(`round trip`, func(t *testing.T) {
+		root := tree()
+		data, err := ast.MarshalJSON(root)
+		if !assert.NoError(t, err) {
+			return
+		}
+		decoded, err := ast.UnmarshalJSON(data)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if !assert.Equal(t, root, decoded) {
+			return
+		}
+		inner := decoded.(*ast.Container).Children[1].(*ast.Container)
+		assert.Equal(t, inner, inner.Children[0].GetParent())
+	})
+	t.Run(`error`, func(t *testing.T) {
+		node := ast.NewError(errors.New(`broken`), 'x')
+		node.SetSpan(ast.Span{Start: 1, End: 2})
+		root := ast.NewContainer(node, ast.NewError(nil, 'y'))
+		data, err := ast.MarshalJSON(root)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.JSONEq(t, `{
+			"type": "container",
+			"span": {"start": 0, "end": 0},
+			"children": [
+				{"type": "error", "span": {"start": 1, "end": 2}, "content": "x", "attributes": {"error": "broken"}},
+				{"type": "error", "span": {"start": 0, "end": 0}, "content": "y"}
+			]
+		}`, string(data))
+		decoded, err := ast.UnmarshalJSON(data)
+		if assert.NoError(t, err) {
+			assert.True(t, ast.Equal(root, decoded))
+		}
+	})
+	t.Run(`custom`, func(t *testing.T) {
+		registry := emphasisRegistry()
+		root := ast.NewContainer(&emphasis{Container: ast.NewContainer(ast.NewText([]byte(`e`)...)), strong: true})
+		data, err := registry.Marshal(root)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Contains(t, string(data), `"type":"emphasis","span":{"start":0,"end":0},"attributes":{"strong":true}`)
+		decoded, err := registry.Unmarshal(data)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, ast.Equal(root, decoded))
+		assert.True(t, decoded.(*ast.Container).Children[0].(*emphasis).strong)
+	})
+	t.Run(`invalid utf8`, func(t *testing.T) {
+		container := ast.NewContainer(ast.NewText([]byte("a\xff\xfeb")...), ast.NewError(nil, 0xc3))
+		container.SetDelimiters([]byte("\xab"), []byte(")"))
+		root := ast.NewContainer(container, ast.NewText([]byte(`é`)...))
+		data, err := ast.MarshalJSON(root)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.JSONEq(t, `{
+			"type": "container",
+			"span": {"start": 0, "end": 0},
+			"children": [
+				{
+					"type": "container",
+					"span": {"start": 0, "end": 0},
+					"opening": {"base64": "qw=="},
+					"closing": ")",
+					"children": [
+						{"type": "text", "span": {"start": 0, "end": 0}, "content": {"base64": "Yf/+Yg=="}},
+						{"type": "error", "span": {"start": 0, "end": 0}, "content": {"base64": "ww=="}}
+					]
+				},
+				{"type": "text", "span": {"start": 0, "end": 0}, "content": "é"}
+			]
+		}`, string(data))
+		decoded, err := ast.UnmarshalJSON(data)
+		if assert.NoError(t, err) {
+			assert.True(t, ast.Equal(root, decoded), ast.Diff(root, decoded))
+		}
+		_, err = ast.UnmarshalJSON([]byte(`{"type":"text","content":{"base64":1}}`))
+		assert.Error(t, err)
+	})
+	t.Run(`unregistered`, func(t *testing.T) {
+		_, err := ast.MarshalJSON(ast.NewContainer(&emphasis{Container: ast.NewContainer()}))
+		assert.EqualError(t, err, `yaastr: unregistered node type *ast_test.emphasis`)
+		_, err = ast.UnmarshalJSON([]byte(`{"type":"emphasis"}`))
+		assert.EqualError(t, err, `yaastr: unregistered node type "emphasis"`)
+	})
+	t.Run(`text with children`, func(t *testing.T) {
+		_, err := ast.UnmarshalJSON([]byte(`{"type":"text","children":[{"type":"text"}]}`))
+		assert.EqualError(t, err, `yaastr: node type "text" can not have children`)
+	})
+	t.Run(`invalid`, func(t *testing.T) {
+		_, err := ast.UnmarshalJSON([]byte(`{`))
+		assert.Error(t, err)
+	})
+}
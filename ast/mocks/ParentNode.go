@@ -81,6 +81,18 @@ func (_m *ParentNode) PrependNode(_a0 ...ast.Node) {
 	_m.Called(_ca...)
 }
 
+// ReplaceNode provides a mock function with given fields: _a0, _a1
+func (_m *ParentNode) ReplaceNode(_a0 int, _a1 ...ast.Node) {
+	_va := make([]interface{}, len(_a1))
+	for _i := range _a1 {
+		_va[_i] = _a1[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _a0)
+	_ca = append(_ca, _va...)
+	_m.Called(_ca...)
+}
+
 // SetParent provides a mock function with given fields: node
 func (_m *ParentNode) SetParent(node ast.ParentNode) {
 	_m.Called(node)
@@ -39,13 +39,17 @@ type (
 		PrependNode(...Node)
 		DeleteNode(index int)
 		InsertNode(int, ...Node)
+		ReplaceNode(int, ...Node)
 	}
 	Child struct {
 		Parent ParentNode
+		Span   Span
 	}
 	Container struct {
 		Child
 		Children []Node
+		Opening  []byte
+		Closing  []byte
 	}
 	Text struct {
 		Child
@@ -54,6 +58,12 @@ type (
 	Document struct {
 		Container
 	}
+	//Error replaces input which could not be parsed
+	Error struct {
+		Child
+		Content []byte
+		Err     error
+	}
 )
 
 //GetParent
@@ -71,6 +81,11 @@ func NewText(content ...byte) *Text {
 	return &Text{Content: content}
 }
 
+//NewError
+func NewError(err error, content ...byte) *Error {
+	return &Error{Content: content, Err: err}
+}
+
 //NewContainer
 func NewContainer(children ...Node) *Container {
 	container := &Container{Children: children}
@@ -141,6 +156,18 @@ func (c *Container) DeleteNode(index int) {
 	c.Children = append(c.Children[:index], c.Children[index+1:]...)
 }
 
+//ReplaceNode
+func (c *Container) ReplaceNode(index int, nodes ...Node) {
+	if index < 0 || index >= len(c.Children) {
+		return
+	}
+	setParent(c, nodes...)
+	children := make([]Node, 0, len(c.Children)-1+len(nodes))
+	children = append(children, c.Children[:index]...)
+	children = append(children, nodes...)
+	c.Children = append(children, c.Children[index+1:]...)
+}
+
 func setParent(parent ParentNode, nodes ...Node) {
 	for _, node := range nodes {
 		node.SetParent(parent)
@@ -208,4 +208,51 @@ func TestContainer_DeleteNode(t *testing.T) {
 			return
 		}
 	})
-}
\ No newline at end of file
+}
+
+func TestContainer_ReplaceNode(t *testing.T) {
+	text1 := ast.NewText([]byte(`text1`)...)
+	text2 := ast.NewText([]byte(`text2`)...)
+	text3 := ast.NewText([]byte(`text3`)...)
+	t.Run(`empty`, func(t *testing.T) {
+		c := ast.NewContainer()
+		c.ReplaceNode(0, text1)
+		if !assert.Equal(t, []ast.Node(nil), c.Children) {
+			return
+		}
+	})
+	t.Run(`middle`, func(t *testing.T) {
+		c := ast.NewContainer(text1, text2, text3)
+		text := ast.NewText([]byte(`text`)...)
+		c.ReplaceNode(1, text)
+		if !assert.Equal(t, []ast.Node{text1, text, text3}, c.Children) {
+			return
+		}
+		if !assert.Equal(t, c, text.GetParent()) {
+			return
+		}
+	})
+	t.Run(`many`, func(t *testing.T) {
+		c := ast.NewContainer(text1, text3)
+		text := ast.NewText([]byte(`text`)...)
+		c.ReplaceNode(1, text2, text)
+		if !assert.Equal(t, []ast.Node{text1, text2, text}, c.Children) {
+			return
+		}
+	})
+	t.Run(`none`, func(t *testing.T) {
+		c := ast.NewContainer(text1, text2, text3)
+		c.ReplaceNode(0)
+		if !assert.Equal(t, []ast.Node{text2, text3}, c.Children) {
+			return
+		}
+	})
+	t.Run(`out of range`, func(t *testing.T) {
+		c := ast.NewContainer(text1, text2)
+		c.ReplaceNode(2, text3)
+		c.ReplaceNode(-1, text3)
+		if !assert.Equal(t, []ast.Node{text1, text2}, c.Children) {
+			return
+		}
+	})
+}
@@ -0,0 +1,92 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast
+
+import "io"
+
+type (
+	Delimited interface {
+		GetDelimiters() (opening, closing []byte)
+		SetDelimiters(opening, closing []byte)
+	}
+	renderer struct {
+		writer io.Writer
+		err    error
+	}
+)
+
+//GetDelimiters
+func (c *Container) GetDelimiters() (opening, closing []byte) {
+	return c.Opening, c.Closing
+}
+
+//SetDelimiters
+func (c *Container) SetDelimiters(opening, closing []byte) {
+	c.Opening, c.Closing = opening, closing
+}
+
+//Render writes content of text and error nodes and delimiters of Delimited nodes in tree order
+func Render(w io.Writer, node Node) error {
+	r := &renderer{writer: w}
+	Walk(node, r)
+	return r.err
+}
+
+//Enter
+func (r *renderer) Enter(node Node) WalkStatus {
+	switch n := node.(type) {
+	case *Text:
+		r.write(n.Content)
+	case *Error:
+		r.write(n.Content)
+	case Delimited:
+		opening, _ := n.GetDelimiters()
+		r.write(opening)
+	}
+	return r.status()
+}
+
+//Leave
+func (r *renderer) Leave(node Node) WalkStatus {
+	if delimited, ok := node.(Delimited); ok {
+		_, closing := delimited.GetDelimiters()
+		r.write(closing)
+	}
+	return r.status()
+}
+
+func (r *renderer) write(data []byte) {
+	if len(data) > 0 && r.err == nil {
+		_, r.err = r.writer.Write(data)
+	}
+}
+
+func (r *renderer) status() WalkStatus {
+	if r.err != nil {
+		return WalkStop
+	}
+	return WalkContinue
+}
@@ -0,0 +1,105 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type failingWriter struct {
+	written int
+}
+
+func (w *failingWriter) Write(data []byte) (int, error) {
+	if w.written > 0 {
+		return 0, errors.New(`closed`)
+	}
+	w.written += len(data)
+	return len(data), nil
+}
+
+func delimitedTree() *ast.Document {
+	inner := ast.NewContainer(ast.NewText([]byte(`b`)...))
+	inner.SetDelimiters([]byte(`(`), []byte(`)`))
+	doc := &ast.Document{}
+	doc.AppendNode(ast.NewText([]byte(`a `)...), inner, ast.NewText([]byte(` c`)...))
+	return doc
+}
+
+func TestRender(t *testing.T) {
+	t.Run(`delimiters`, func(t *testing.T) {
+		var rendered bytes.Buffer
+		if !assert.NoError(t, ast.Render(&rendered, delimitedTree())) {
+			return
+		}
+		assert.Equal(t, `a (b) c`, rendered.String())
+	})
+	t.Run(`without delimiters`, func(t *testing.T) {
+		var rendered bytes.Buffer
+		if !assert.NoError(t, ast.Render(&rendered, tree())) {
+			return
+		}
+		assert.Equal(t, `abc`, rendered.String())
+	})
+	t.Run(`error node`, func(t *testing.T) {
+		var rendered bytes.Buffer
+		root := ast.NewContainer(ast.NewText('a'), ast.NewError(nil, []byte(` 'b`)...))
+		if assert.NoError(t, ast.Render(&rendered, root)) {
+			assert.Equal(t, `a 'b`, rendered.String())
+		}
+	})
+	t.Run(`error`, func(t *testing.T) {
+		w := &failingWriter{}
+		assert.EqualError(t, ast.Render(w, delimitedTree()), `closed`)
+		assert.Equal(t, 2, w.written)
+	})
+	t.Run(`clone`, func(t *testing.T) {
+		var rendered bytes.Buffer
+		if !assert.NoError(t, ast.Render(&rendered, ast.Clone(delimitedTree()))) {
+			return
+		}
+		assert.Equal(t, `a (b) c`, rendered.String())
+	})
+	t.Run(`json`, func(t *testing.T) {
+		data, err := ast.MarshalJSON(delimitedTree())
+		if !assert.NoError(t, err) {
+			return
+		}
+		decoded, err := ast.UnmarshalJSON(data)
+		if !assert.NoError(t, err) {
+			return
+		}
+		var rendered bytes.Buffer
+		if !assert.NoError(t, ast.Render(&rendered, decoded)) {
+			return
+		}
+		assert.Equal(t, `a (b) c`, rendered.String())
+	})
+}
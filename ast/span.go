@@ -0,0 +1,99 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast
+
+import "unicode/utf8"
+
+type (
+	Span struct {
+		Start int `json:"start"`
+		End   int `json:"end"`
+	}
+	Position struct {
+		Offset int
+		Line   int
+		Column int
+	}
+	Spanned interface {
+		GetSpan() Span
+		SetSpan(span Span)
+	}
+)
+
+//GetSpan
+func (c *Child) GetSpan() Span {
+	return c.Span
+}
+
+//SetSpan
+func (c *Child) SetSpan(span Span) {
+	c.Span = span
+}
+
+//Len
+func (s Span) Len() int {
+	return s.End - s.Start
+}
+
+//Bytes returns part of data covered by span
+func (s Span) Bytes(data []byte) []byte {
+	return data[clamp(s.Start, len(data)):clamp(s.End, len(data))]
+}
+
+//PositionOf converts byte offset in data to one-based line and column, column is counted in runes
+func PositionOf(data []byte, offset int) Position {
+	offset = clamp(offset, len(data))
+	position := Position{Offset: offset, Line: 1, Column: 1}
+	for index := 0; index < offset; {
+		r, size := utf8.DecodeRune(data[index:])
+		index += size
+		if r == '\n' {
+			position.Line++
+			position.Column = 1
+			continue
+		}
+		position.Column++
+	}
+	return position
+}
+
+//SpanOf returns span of node if node is Spanned
+func SpanOf(node Node) (Span, bool) {
+	if spanned, ok := node.(Spanned); ok {
+		return spanned.GetSpan(), true
+	}
+	return Span{}, false
+}
+
+func clamp(offset, length int) int {
+	if offset < 0 {
+		return 0
+	}
+	if offset > length {
+		return length
+	}
+	return offset
+}
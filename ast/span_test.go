@@ -0,0 +1,75 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast_test
+
+import (
+	"github.com/biodebox/yaastr/ast"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPositionOf(t *testing.T) {
+	data := []byte("first\nвторой\n\nthird")
+	t.Run(`start`, func(t *testing.T) {
+		assert.Equal(t, ast.Position{Offset: 0, Line: 1, Column: 1}, ast.PositionOf(data, 0))
+	})
+	t.Run(`line feed`, func(t *testing.T) {
+		assert.Equal(t, ast.Position{Offset: 5, Line: 1, Column: 6}, ast.PositionOf(data, 5))
+		assert.Equal(t, ast.Position{Offset: 6, Line: 2, Column: 1}, ast.PositionOf(data, 6))
+	})
+	t.Run(`multibyte`, func(t *testing.T) {
+		assert.Equal(t, ast.Position{Offset: 10, Line: 2, Column: 3}, ast.PositionOf(data, 10))
+	})
+	t.Run(`empty line`, func(t *testing.T) {
+		assert.Equal(t, ast.Position{Offset: 19, Line: 3, Column: 1}, ast.PositionOf(data, 19))
+		assert.Equal(t, ast.Position{Offset: 20, Line: 4, Column: 1}, ast.PositionOf(data, 20))
+	})
+	t.Run(`out of range`, func(t *testing.T) {
+		assert.Equal(t, ast.Position{Offset: 0, Line: 1, Column: 1}, ast.PositionOf(data, -1))
+		assert.Equal(t, ast.Position{Offset: 25, Line: 4, Column: 6}, ast.PositionOf(data, 100))
+	})
+}
+
+func TestSpan(t *testing.T) {
+	data := []byte(`first second`)
+	t.Run(`bytes`, func(t *testing.T) {
+		span := ast.Span{Start: 6, End: 12}
+		assert.Equal(t, 6, span.Len())
+		assert.Equal(t, []byte(`second`), span.Bytes(data))
+	})
+	t.Run(`bytes out of range`, func(t *testing.T) {
+		assert.Equal(t, []byte(`second`), ast.Span{Start: 6, End: 100}.Bytes(data))
+	})
+	t.Run(`node`, func(t *testing.T) {
+		text := ast.NewText(data...)
+		text.SetSpan(ast.Span{End: len(data)})
+		span, ok := ast.SpanOf(text)
+		if !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, ast.Span{End: 12}, span)
+	})
+}
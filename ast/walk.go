@@ -0,0 +1,88 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast
+
+type (
+	Visitor interface {
+		Enter(node Node) WalkStatus
+		Leave(node Node) WalkStatus
+	}
+	WalkStatus int
+	inspector  func(Node) bool
+)
+
+const (
+	//WalkContinue visits children of entered node
+	WalkContinue WalkStatus = iota
+	//WalkSkipChildren skips children of entered node, Leave is still called
+	WalkSkipChildren
+	//WalkStop stops walking
+	WalkStop
+)
+
+//Walk traverses tree depth-first calling Enter before and Leave after children of node
+func Walk(node Node, visitor Visitor) {
+	walk(node, visitor)
+}
+
+//Inspect traverses tree calling f for each node, children are skipped if f returns false
+func Inspect(node Node, f func(Node) bool) {
+	Walk(node, inspector(f))
+}
+
+func walk(node Node, visitor Visitor) WalkStatus {
+	if node == nil {
+		return WalkContinue
+	}
+	status := visitor.Enter(node)
+	if status == WalkStop {
+		return WalkStop
+	}
+	if parent, ok := node.(ParentNode); ok && status != WalkSkipChildren {
+		for _, child := range parent.GetChildren() {
+			if walk(child, visitor) == WalkStop {
+				return WalkStop
+			}
+		}
+	}
+	if visitor.Leave(node) == WalkStop {
+		return WalkStop
+	}
+	return WalkContinue
+}
+
+//Enter
+func (f inspector) Enter(node Node) WalkStatus {
+	if f(node) {
+		return WalkContinue
+	}
+	return WalkSkipChildren
+}
+
+//Leave
+func (f inspector) Leave(Node) WalkStatus {
+	return WalkContinue
+}
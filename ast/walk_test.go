@@ -0,0 +1,155 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package ast_test
+
+import (
+	"fmt"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type recorder struct {
+	events []string
+	enter  func(node ast.Node) ast.WalkStatus
+	leave  func(node ast.Node) ast.WalkStatus
+}
+
+func (r *recorder) Enter(node ast.Node) ast.WalkStatus {
+	r.events = append(r.events, `enter `+name(node))
+	if r.enter != nil {
+		return r.enter(node)
+	}
+	return ast.WalkContinue
+}
+
+func (r *recorder) Leave(node ast.Node) ast.WalkStatus {
+	r.events = append(r.events, `leave `+name(node))
+	if r.leave != nil {
+		return r.leave(node)
+	}
+	return ast.WalkContinue
+}
+
+func name(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.Text:
+		return string(n.Content)
+	case *ast.Container:
+		return fmt.Sprintf(`container(%d)`, len(n.Children))
+	}
+	return fmt.Sprintf(`%T`, node)
+}
+
+func tree() *ast.Container {
+	return ast.NewContainer(
+		ast.NewText([]byte(`a`)...),
+		ast.NewContainer(ast.NewText([]byte(`b`)...)),
+		ast.NewText([]byte(`c`)...),
+	)
+}
+
+func TestWalk(t *testing.T) {
+	t.Run(`order`, func(t *testing.T) {
+		r := &recorder{}
+		ast.Walk(tree(), r)
+		assert.Equal(t, []string{
+			`enter container(3)`,
+			`enter a`,
+			`leave a`,
+			`enter container(1)`,
+			`enter b`,
+			`leave b`,
+			`leave container(1)`,
+			`enter c`,
+			`leave c`,
+			`leave container(3)`,
+		}, r.events)
+	})
+	t.Run(`skip children`, func(t *testing.T) {
+		r := &recorder{enter: func(node ast.Node) ast.WalkStatus {
+			if name(node) == `container(1)` {
+				return ast.WalkSkipChildren
+			}
+			return ast.WalkContinue
+		}}
+		ast.Walk(tree(), r)
+		assert.Equal(t, []string{
+			`enter container(3)`,
+			`enter a`,
+			`leave a`,
+			`enter container(1)`,
+			`leave container(1)`,
+			`enter c`,
+			`leave c`,
+			`leave container(3)`,
+		}, r.events)
+	})
+	t.Run(`stop on enter`, func(t *testing.T) {
+		r := &recorder{enter: func(node ast.Node) ast.WalkStatus {
+			if name(node) == `b` {
+				return ast.WalkStop
+			}
+			return ast.WalkContinue
+		}}
+		ast.Walk(tree(), r)
+		assert.Equal(t, []string{
+			`enter container(3)`,
+			`enter a`,
+			`leave a`,
+			`enter container(1)`,
+			`enter b`,
+		}, r.events)
+	})
+	t.Run(`stop on leave`, func(t *testing.T) {
+		r := &recorder{leave: func(node ast.Node) ast.WalkStatus {
+			if name(node) == `a` {
+				return ast.WalkStop
+			}
+			return ast.WalkContinue
+		}}
+		ast.Walk(tree(), r)
+		assert.Equal(t, []string{
+			`enter container(3)`,
+			`enter a`,
+			`leave a`,
+		}, r.events)
+	})
+	t.Run(`nil`, func(t *testing.T) {
+		r := &recorder{}
+		ast.Walk(nil, r)
+		assert.Empty(t, r.events)
+	})
+}
+
+func TestInspect(t *testing.T) {
+	var names []string
+	ast.Inspect(tree(), func(node ast.Node) bool {
+		names = append(names, name(node))
+		return name(node) != `container(1)`
+	})
+	assert.Equal(t, []string{`container(3)`, `a`, `container(1)`, `c`}, names)
+}
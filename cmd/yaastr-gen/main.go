@@ -0,0 +1,93 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+//Command yaastr-gen generates Go parser from PEG grammar.
+//
+//	yaastr-gen [-package name] [-o output.go] grammar.peg
+//
+//Generated package has New and Processor functions building the same tree as peg.New for the grammar.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/biodebox/yaastr/parser/peg"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	pkg := flag.String("package", "", "package name, default is name of output directory")
+	output := flag.String("o", "", "output file, default is grammar file with .go extension")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: yaastr-gen [-package name] [-o output.go] grammar.peg\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := generate(flag.Arg(0), *output, *pkg); err != nil {
+		if list, ok := err.(parser.ErrorList); ok {
+			for _, err := range list {
+				fmt.Fprintf(os.Stderr, "%s:%v\n", flag.Arg(0), err)
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "yaastr-gen: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+func generate(input, output, pkg string) error {
+	source, err := ioutil.ReadFile(input)
+	if err != nil {
+		return err
+	}
+	grammar, err := peg.Load(source)
+	if err != nil {
+		return err
+	}
+	if output == "" {
+		output = strings.TrimSuffix(input, filepath.Ext(input)) + ".go"
+	}
+	if pkg == "" {
+		directory, err := filepath.Abs(filepath.Dir(output))
+		if err != nil {
+			return err
+		}
+		pkg = filepath.Base(directory)
+	}
+	buffer := &bytes.Buffer{}
+	if err := grammar.Generate(buffer, pkg); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(output, buffer.Bytes(), 0644)
+}
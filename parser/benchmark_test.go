@@ -0,0 +1,104 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser_test
+
+import (
+	"bytes"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"strings"
+	"testing"
+)
+
+func benchmarkProcessors(p parser.Parser, declare bool) {
+	for index := 0; index < 30; index++ {
+		opening := string(rune('A' + index))
+		processor := parser.ProcessorByStrings(opening+`{`, `}`, func() ast.ParentNode {
+			return ast.NewContainer()
+		})
+		if declare {
+			p.AddProcessorFor(opening, processor)
+		} else {
+			p.AddProcessor(processor)
+		}
+	}
+}
+
+func benchmarkInput() []byte {
+	return []byte(strings.Repeat(`plain text of the document with B{marked} words and some more text `, 10000))
+}
+
+func BenchmarkParser_Parse(b *testing.B) {
+	input := benchmarkInput()
+	for _, test := range []struct {
+		name    string
+		declare bool
+		options []parser.Option
+	}{
+		{name: `all processors`},
+		{name: `dispatch`, declare: true},
+		{name: `dispatch copy text`, declare: true, options: []parser.Option{parser.CopyText()}},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			p := parser.New()
+			p.SetOptions(test.options...)
+			benchmarkProcessors(p, test.declare)
+			benchmarkParse(b, p, input)
+		})
+	}
+}
+
+func BenchmarkParser_Parse_Text(b *testing.B) {
+	input := []byte(strings.Repeat(`plain text without any markup `, 100000))
+	b.Run(`shared`, func(b *testing.B) {
+		benchmarkParse(b, parser.New(processorQuote()), input)
+	})
+	b.Run(`copy`, func(b *testing.B) {
+		p := parser.New(processorQuote())
+		p.SetOptions(parser.CopyText())
+		benchmarkParse(b, p, input)
+	})
+	b.Run(`reader`, func(b *testing.B) {
+		p := parser.New(processorQuote())
+		b.SetBytes(int64(len(input)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := p.ParseReader(bytes.NewReader(input)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func benchmarkParse(b *testing.B, p parser.Parser, input []byte) {
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := p.Parse(input); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package combinator
+
+import (
+	"fmt"
+)
+
+type (
+	class struct {
+		ranges []runeRange
+		negate bool
+	}
+	runeRange struct {
+		low, high rune
+	}
+)
+
+func newClass(value string) *class {
+	c := &class{}
+	runes, escaped := unescapeClass(value)
+	if len(runes) > 1 && runes[0] == '^' && !escaped[0] {
+		c.negate = true
+		runes, escaped = runes[1:], escaped[1:]
+	}
+	if len(runes) == 0 {
+		panic("yaastr: empty class")
+	}
+	for i := 0; i < len(runes); i++ {
+		r := runeRange{low: runes[i], high: runes[i]}
+		if i+2 < len(runes) && runes[i+1] == '-' && !escaped[i+1] {
+			r.high = runes[i+2]
+			i += 2
+		}
+		if r.low > r.high {
+			panic(fmt.Sprintf("yaastr: invalid class %q", value))
+		}
+		c.ranges = append(c.ranges, r)
+	}
+	return c
+}
+
+//unescapeClass removes backslashes marking escaped runes
+func unescapeClass(value string) ([]rune, []bool) {
+	runes := make([]rune, 0, len(value))
+	escaped := make([]bool, 0, len(value))
+	for i, source := 0, []rune(value); i < len(source); i++ {
+		if source[i] == '\\' && i+1 < len(source) {
+			i++
+			runes, escaped = append(runes, source[i]), append(escaped, true)
+			continue
+		}
+		runes, escaped = append(runes, source[i]), append(escaped, false)
+	}
+	return runes, escaped
+}
+
+func (c *class) match(r rune) bool {
+	for _, rr := range c.ranges {
+		if r >= rr.low && r <= rr.high {
+			return !c.negate
+		}
+	}
+	return c.negate
+}
@@ -0,0 +1,307 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+//Package combinator composes grammars from small rules and converts them to parser processors
+package combinator
+
+import (
+	"bytes"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"strconv"
+	"unicode/utf8"
+)
+
+type (
+	//Rule matches beginning of data and appends created nodes to node, ok is false when data does not match.
+	//Unlike Processor a rule may match empty input.
+	Rule func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (n int, ok bool, err error)
+)
+
+//Processor converts rule to parser processor, empty match is reported as no match.
+//Text of matched input not covered by nodes is appended as text nodes.
+func (r Rule) Processor() parser.Processor {
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, error) {
+		index := len(node.GetChildren())
+		n, ok, err := r(node, data, parse)
+		if err != nil || !ok || n == 0 {
+			Truncate(node, index)
+			return 0, err
+		}
+		if err := fill(node, index, data[:n], -1, parse); err != nil {
+			return 0, err
+		}
+		return n, nil
+	}
+}
+
+//Literal matches literal string
+func Literal(literal string) Rule {
+	if len(literal) == 0 {
+		panic("yaastr: empty literal")
+	}
+	value := []byte(literal)
+	expected := strconv.Quote(literal)
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		if bytes.HasPrefix(data, value) {
+			return len(value), true, nil
+		}
+		if len(data) < len(value) && bytes.HasPrefix(value, data) && parser.More(parse, data) {
+			return 0, false, parser.ErrIncomplete
+		}
+		parser.Expect(parse, data, expected)
+		return 0, false, nil
+	}
+}
+
+//CharClass matches one rune of class like "a-zA-Z_", leading ^ negates class, - is literal at start or end, backslash escapes next rune
+func CharClass(class string) Rule {
+	c := newClass(class)
+	expected := "[" + class + "]"
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		if !utf8.FullRune(data) && parser.More(parse, data) {
+			return 0, false, parser.ErrIncomplete
+		}
+		if len(data) > 0 {
+			if r, size := utf8.DecodeRune(data); c.match(r) {
+				return size, true, nil
+			}
+		}
+		parser.Expect(parse, data, expected)
+		return 0, false, nil
+	}
+}
+
+//Any matches any rune
+func Any() Rule {
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		if !utf8.FullRune(data) && parser.More(parse, data) {
+			return 0, false, parser.ErrIncomplete
+		}
+		if len(data) == 0 {
+			parser.Expect(parse, data, "any rune")
+			return 0, false, nil
+		}
+		_, size := utf8.DecodeRune(data)
+		return size, true, nil
+	}
+}
+
+//Seq matches all rules one after another
+func Seq(rules ...Rule) Rule {
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		index := len(node.GetChildren())
+		offset := 0
+		for _, rule := range rules {
+			n, ok, err := rule(node, data[offset:], parse)
+			if err != nil || !ok {
+				Truncate(node, index)
+				return 0, false, err
+			}
+			offset += n
+		}
+		return offset, true, nil
+	}
+}
+
+//Choice matches first matching rule
+func Choice(rules ...Rule) Rule {
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		index := len(node.GetChildren())
+		for _, rule := range rules {
+			n, ok, err := rule(node, data, parse)
+			if err != nil {
+				Truncate(node, index)
+				return 0, false, err
+			}
+			if ok {
+				return n, true, nil
+			}
+			Truncate(node, index)
+		}
+		return 0, false, nil
+	}
+}
+
+//Many matches rule zero or more times, repetition stops on empty match
+func Many(rule Rule) Rule {
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		offset := 0
+		for {
+			index := len(node.GetChildren())
+			n, ok, err := rule(node, data[offset:], parse)
+			if err != nil {
+				return 0, false, err
+			}
+			if !ok {
+				Truncate(node, index)
+				return offset, true, nil
+			}
+			offset += n
+			if n == 0 {
+				return offset, true, nil
+			}
+		}
+	}
+}
+
+//Many1 matches rule one or more times
+func Many1(rule Rule) Rule {
+	return Seq(rule, Many(rule))
+}
+
+//Optional matches rule or empty input
+func Optional(rule Rule) Rule {
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		index := len(node.GetChildren())
+		n, ok, err := rule(node, data, parse)
+		if err != nil {
+			return 0, false, err
+		}
+		if !ok {
+			Truncate(node, index)
+			return 0, true, nil
+		}
+		return n, true, nil
+	}
+}
+
+//Not matches empty input when rule does not match, nothing is consumed and failures of rule are not expectations
+func Not(rule Rule) Rule {
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		index := len(node.GetChildren())
+		_, ok, err := rule(node, data, parser.Quiet(parse))
+		Truncate(node, index)
+		return 0, !ok && err == nil, err
+	}
+}
+
+//Lookahead matches empty input when rule matches, nothing is consumed and failures of rule are not expectations
+func Lookahead(rule Rule) Rule {
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		index := len(node.GetChildren())
+		_, ok, err := rule(node, data, parser.Quiet(parse))
+		Truncate(node, index)
+		return 0, ok && err == nil, err
+	}
+}
+
+//Map creates node by factory for input matched by rule, the node gets nodes of rule and text between them.
+//The node counts as nesting level for MaxDepth option.
+func Map(rule Rule, nodeFactory func() ast.ParentNode) Rule {
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		child := nodeFactory()
+		n, ok, err := parser.Descend(parse, child, data, rule)
+		if err != nil || !ok {
+			return 0, false, err
+		}
+		if err := Attach(node, child, data[:n], parse); err != nil {
+			return 0, false, err
+		}
+		return n, true, nil
+	}
+}
+
+//Attach appends child which nodes were created for data as Map does, it is used by generated parsers
+func Attach(node, child ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) error {
+	start := parser.Offset(parse, data)
+	if err := fill(child, 0, data, start, parse); err != nil {
+		return err
+	}
+	if spanned, ok := child.(ast.Spanned); ok {
+		spanned.SetSpan(ast.Span{Start: start, End: start + len(data)})
+	}
+	node.AppendNode(child)
+	return nil
+}
+
+//Expect fails with *parser.ExpectedError listing input expected at the farthest offset when rule does not match,
+//the error is reported as *parser.Error with Expected field
+func Expect(rule Rule) Rule {
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		n, ok, err := rule(node, data, parse)
+		if err != nil || ok {
+			return n, ok, err
+		}
+		if err := parser.Expected(parse, data); err != nil {
+			return 0, false, err
+		}
+		return 0, false, &parser.ExpectedError{Offset: parser.Offset(parse, data)}
+	}
+}
+
+//Memo caches results of rule by position when parser has Memo option,
+//grammar which rules called at the same position are memoized is parsed in linear time
+func Memo(rule Rule) Rule {
+	key := &rule
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		return parser.Memoize(parse, key, node, data, rule)
+	}
+}
+
+//Ref refers to rule which is assigned later, it allows recursive grammars.
+//Parsing stops when context of ParseContext is done.
+func Ref(rule *Rule) Rule {
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		if err := parser.Done(parse, data); err != nil {
+			return 0, false, err
+		}
+		return (*rule)(node, data, parse)
+	}
+}
+
+//Truncate deletes children of node added after index, it is used by generated parsers to backtrack
+func Truncate(node ast.ParentNode, index int) {
+	for length := len(node.GetChildren()); length > index; length-- {
+		node.DeleteNode(length - 1)
+	}
+}
+
+//fill inserts text of data between children of node added after index, children spans must lie inside data.
+//Base is offset of data or -1 when it is not known yet.
+func fill(node ast.ParentNode, index int, data []byte, base int, parse func(ast.ParentNode, []byte) error) error {
+	if len(node.GetChildren()) == index {
+		return parse(parser.Literal(node), data)
+	}
+	children := append([]ast.Node(nil), node.GetChildren()[index:]...)
+	Truncate(node, index)
+	if base < 0 {
+		base = parser.Offset(parse, data)
+	}
+	offset := 0
+	for _, child := range children {
+		if span, ok := ast.SpanOf(child); ok && span.Start-base >= offset && span.End-base <= len(data) {
+			if err := parse(parser.Literal(node), data[offset:span.Start-base]); err != nil {
+				return err
+			}
+			offset = span.End - base
+		}
+		node.AppendNode(child)
+	}
+	if offset < len(data) {
+		return parse(parser.Literal(node), data[offset:])
+	}
+	return nil
+}
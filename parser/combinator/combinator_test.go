@@ -0,0 +1,352 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package combinator_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	. "github.com/biodebox/yaastr/parser/combinator"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+type (
+	list struct {
+		*ast.Container
+	}
+	word struct {
+		*ast.Container
+	}
+)
+
+func (l *list) CloneNode() ast.Node {
+	return &list{Container: ast.NewContainer()}
+}
+
+func (w *word) CloneNode() ast.Node {
+	return &word{Container: ast.NewContainer()}
+}
+
+func newList() ast.ParentNode {
+	return &list{Container: ast.NewContainer()}
+}
+
+func newWord() ast.ParentNode {
+	return &word{Container: ast.NewContainer()}
+}
+
+func noop(ast.ParentNode, []byte) error {
+	return nil
+}
+
+//grammar matches lists like (a (b c)) of words
+func grammar() Rule {
+	var item Rule
+	space := Many(CharClass(" \t\n"))
+	item = Choice(
+		Map(Many1(CharClass("a-zA-Z0-9_")), newWord),
+		Map(Seq(Literal("("), space, Many(Seq(Ref(&item), space)), Literal(")")), newList),
+	)
+	return item
+}
+
+func TestRule(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		rule  Rule
+		input string
+		n     int
+		ok    bool
+	}{
+		{name: `literal`, rule: Literal(`ab`), input: `abc`, n: 2, ok: true},
+		{name: `literal mismatch`, rule: Literal(`ab`), input: `ac`},
+		{name: `literal short`, rule: Literal(`ab`), input: `a`},
+		{name: `class`, rule: CharClass(`a-z`), input: `q`, n: 1, ok: true},
+		{name: `class mismatch`, rule: CharClass(`a-z`), input: `Q`},
+		{name: `class empty input`, rule: CharClass(`a-z`), input: ``},
+		{name: `class list`, rule: CharClass(`a-c_-`), input: `-`, n: 1, ok: true},
+		{name: `class negate`, rule: CharClass(`^a-z`), input: `Q`, n: 1, ok: true},
+		{name: `class negate mismatch`, rule: CharClass(`^a-z`), input: `q`},
+		{name: `class caret`, rule: CharClass(`^`), input: `^`, n: 1, ok: true},
+		{name: `class escaped caret`, rule: CharClass(`\^a`), input: `^`, n: 1, ok: true},
+		{name: `class escaped dash`, rule: CharClass(`a\-z`), input: `-`, n: 1, ok: true},
+		{name: `class escaped dash range`, rule: CharClass(`a\-z`), input: `q`},
+		{name: `class escaped backslash`, rule: CharClass(`\\`), input: `\`, n: 1, ok: true},
+		{name: `class utf8`, rule: CharClass(`а-я`), input: `ж`, n: 2, ok: true},
+		{name: `any`, rule: Any(), input: `жq`, n: 2, ok: true},
+		{name: `any empty input`, rule: Any(), input: ``},
+		{name: `seq`, rule: Seq(Literal(`a`), Literal(`b`)), input: `abc`, n: 2, ok: true},
+		{name: `seq mismatch`, rule: Seq(Literal(`a`), Literal(`b`)), input: `ac`},
+		{name: `seq empty`, rule: Seq(), input: `a`, ok: true},
+		{name: `choice first`, rule: Choice(Literal(`a`), Literal(`ab`)), input: `ab`, n: 1, ok: true},
+		{name: `choice second`, rule: Choice(Literal(`b`), Literal(`ab`)), input: `ab`, n: 2, ok: true},
+		{name: `choice mismatch`, rule: Choice(Literal(`b`), Literal(`c`)), input: `ab`},
+		{name: `many`, rule: Many(Literal(`a`)), input: `aaab`, n: 3, ok: true},
+		{name: `many none`, rule: Many(Literal(`a`)), input: `b`, ok: true},
+		{name: `many empty match`, rule: Many(Optional(Literal(`a`))), input: `aab`, n: 2, ok: true},
+		{name: `many1`, rule: Many1(Literal(`a`)), input: `aab`, n: 2, ok: true},
+		{name: `many1 none`, rule: Many1(Literal(`a`)), input: `b`},
+		{name: `optional`, rule: Optional(Literal(`a`)), input: `a`, n: 1, ok: true},
+		{name: `optional none`, rule: Optional(Literal(`a`)), input: `b`, ok: true},
+		{name: `not`, rule: Not(Literal(`a`)), input: `b`, ok: true},
+		{name: `not mismatch`, rule: Not(Literal(`a`)), input: `a`},
+		{name: `lookahead`, rule: Lookahead(Literal(`a`)), input: `a`, ok: true},
+		{name: `lookahead mismatch`, rule: Lookahead(Literal(`a`)), input: `b`},
+		{name: `map`, rule: Map(Literal(`a`), newWord), input: `a`, n: 1, ok: true},
+		{name: `map mismatch`, rule: Map(Literal(`a`), newWord), input: `b`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			n, ok, err := test.rule(ast.NewContainer(), []byte(test.input), noop)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, test.n, n)
+			assert.Equal(t, test.ok, ok)
+		})
+	}
+	t.Run(`panic`, func(t *testing.T) {
+		assert.Panics(t, func() { Literal(``) })
+		assert.Panics(t, func() { CharClass(``) })
+		assert.Panics(t, func() { CharClass(`z-a`) })
+	})
+	t.Run(`error`, func(t *testing.T) {
+		errRule := errors.New(`rule`)
+		failing := Rule(func(ast.ParentNode, []byte, func(ast.ParentNode, []byte) error) (int, bool, error) {
+			return 0, false, errRule
+		})
+		for _, rule := range []Rule{
+			Seq(Literal(`a`), failing),
+			Choice(Literal(`b`), failing),
+			Many(failing),
+			Optional(failing),
+			Not(failing),
+			Lookahead(failing),
+			Map(failing, newWord),
+		} {
+			_, _, err := rule(ast.NewContainer(), []byte(`a`), noop)
+			assert.Equal(t, errRule, err)
+		}
+	})
+}
+
+func TestRule_Processor(t *testing.T) {
+	t.Run(`tree`, func(t *testing.T) {
+		input := []byte(`x (a (b c) ) y`)
+		node, err := parser.New(grammar().Processor()).Parse(input)
+		if !assert.NoError(t, err) {
+			return
+		}
+		expected := ast.NewContainer(
+			&word{Container: ast.NewContainer(ast.NewText('x'))},
+			ast.NewText(' '),
+			&list{Container: ast.NewContainer(
+				ast.NewText('('),
+				&word{Container: ast.NewContainer(ast.NewText('a'))},
+				ast.NewText(' '),
+				&list{Container: ast.NewContainer(
+					ast.NewText('('),
+					&word{Container: ast.NewContainer(ast.NewText('b'))},
+					ast.NewText(' '),
+					&word{Container: ast.NewContainer(ast.NewText('c'))},
+					ast.NewText(')'),
+				)},
+				ast.NewText(' ', ')'),
+			)},
+			ast.NewText(' '),
+			&word{Container: ast.NewContainer(ast.NewText('y'))},
+		)
+		assert.True(t, ast.Equal(&ast.Document{Container: *expected}, node), ast.Diff(&ast.Document{Container: *expected}, node))
+		inner := node.(*ast.Document).Children[2].(*list).Children[3].(*list)
+		assert.Equal(t, ast.Span{Start: 5, End: 10}, inner.Span)
+		assert.Equal(t, ast.Span{Start: 6, End: 7}, inner.Children[1].(*word).Children[0].(*ast.Text).Span)
+		buffer := &bytes.Buffer{}
+		if assert.NoError(t, ast.Render(buffer, node)) {
+			assert.Equal(t, input, buffer.Bytes())
+		}
+	})
+	t.Run(`backtracking`, func(t *testing.T) {
+		rule := Choice(
+			Seq(Map(Literal(`a`), newWord), Literal(`!`)),
+			Map(Seq(Map(Literal(`a`), newWord), Literal(`?`)), newList),
+		)
+		node, err := parser.New(rule.Processor()).Parse([]byte(`a?`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		children := node.(*ast.Document).Children
+		if !assert.Len(t, children, 1) {
+			return
+		}
+		l := children[0].(*list)
+		if assert.Len(t, l.Children, 2) {
+			assert.IsType(t, &word{}, l.Children[0])
+			assert.Equal(t, []byte(`?`), l.Children[1].(*ast.Text).Content)
+		}
+	})
+	t.Run(`empty match`, func(t *testing.T) {
+		node, err := parser.New(Many(Map(Literal(`a`), newWord)).Processor()).Parse([]byte(`b`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		children := node.(*ast.Document).Children
+		if assert.Len(t, children, 1) {
+			assert.Equal(t, []byte(`b`), children[0].(*ast.Text).Content)
+		}
+	})
+	t.Run(`reader`, func(t *testing.T) {
+		input := strings.Repeat(`(alpha (beta gamma) delta) `, 1000)
+		expected, err := parser.New(grammar().Processor()).Parse([]byte(input))
+		if !assert.NoError(t, err) {
+			return
+		}
+		actual, err := parser.New(grammar().Processor()).ParseReader(iotest.OneByteReader(strings.NewReader(input)))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func TestMemo(t *testing.T) {
+	//nested backtracks twice on every level without memo
+	grammar := func(memo func(Rule) Rule, calls *int) Rule {
+		var nested Rule
+		counted := Rule(func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+			*calls++
+			return Ref(&nested)(node, data, parse)
+		})
+		inner := memo(counted)
+		nested = Choice(
+			Map(Seq(Literal(`(`), inner, Literal(`)x`)), newList),
+			Map(Seq(Literal(`(`), inner, Literal(`)y`)), newList),
+			Map(Literal(`z`), newWord),
+		)
+		return memo(nested)
+	}
+	input := []byte(strings.Repeat(`(`, 16) + `z` + strings.Repeat(`)y`, 16))
+	calls := 0
+	expected, err := parser.New(grammar(func(rule Rule) Rule { return rule }, &calls).Processor()).Parse(input)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 1<<17-2, calls)
+	memoCalls := 0
+	stats := &parser.MemoStats{}
+	p := parser.New(grammar(Memo, &memoCalls).Processor())
+	p.SetOptions(parser.Memo(stats))
+	actual, err := p.Parse(input)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, 16, memoCalls)
+	assert.Equal(t, int64(16), stats.Hits)
+}
+
+func TestExpect(t *testing.T) {
+	p := parser.New(Expect(grammar()).Processor())
+	t.Run(`farthest`, func(t *testing.T) {
+		_, err := p.Parse([]byte("(a (b !"))
+		var parseError *parser.Error
+		if !assert.True(t, errors.As(err, &parseError)) {
+			return
+		}
+		assert.Equal(t, 6, parseError.Offset)
+		assert.Equal(t, []string{"[ \t\n]", `[a-zA-Z0-9_]`, `"("`, `")"`}, parseError.Expected)
+		assert.EqualError(t, err, "1:7: expected [ \t\n], [a-zA-Z0-9_], \"(\" or \")\"")
+	})
+	t.Run(`memo`, func(t *testing.T) {
+		word := Memo(Map(Many1(CharClass(`a-z`)), newWord))
+		rule := Expect(Choice(Seq(word, Literal(`!`)), Seq(word, Literal(`?`))))
+		for _, options := range [][]parser.Option{nil, {parser.Memo(nil)}} {
+			p := parser.New(rule.Processor())
+			p.SetOptions(options...)
+			_, err := p.Parse([]byte(`ab.`))
+			var parseError *parser.Error
+			if assert.True(t, errors.As(err, &parseError)) {
+				assert.Equal(t, []string{`[a-z]`, `"!"`, `"?"`}, parseError.Expected)
+			}
+		}
+	})
+	t.Run(`predicate`, func(t *testing.T) {
+		p := parser.New(Expect(Seq(Not(Literal(`x`)), Literal(`y`))).Processor())
+		_, err := p.Parse([]byte(`z`))
+		var parseError *parser.Error
+		if assert.True(t, errors.As(err, &parseError)) {
+			assert.Equal(t, []string{`"y"`}, parseError.Expected)
+		}
+	})
+	t.Run(`empty input`, func(t *testing.T) {
+		p := parser.New(Expect(Seq(Literal(`a`), Any())).Processor())
+		_, err := p.Parse([]byte(`a`))
+		var parseError *parser.Error
+		if assert.True(t, errors.As(err, &parseError)) {
+			assert.Equal(t, 1, parseError.Offset)
+			assert.Equal(t, []string{`any rune`}, parseError.Expected)
+		}
+	})
+	t.Run(`without parser`, func(t *testing.T) {
+		_, _, err := Expect(Literal(`a`))(newList(), []byte(`b`), noop)
+		assert.EqualError(t, err, `unexpected input`)
+	})
+	t.Run(`match`, func(t *testing.T) {
+		_, err := p.Parse([]byte(`(a (b c))`))
+		assert.NoError(t, err)
+	})
+}
+
+func TestLimits(t *testing.T) {
+	t.Run(`max depth`, func(t *testing.T) {
+		p := parser.New(grammar().Processor())
+		p.SetOptions(parser.MaxDepth(50))
+		_, err := p.Parse([]byte(strings.Repeat(`(`, 1000) + strings.Repeat(`)`, 1000)))
+		assert.True(t, errors.Is(err, parser.ErrMaxDepth), `%v`, err)
+		_, err = p.Parse([]byte(strings.Repeat(`(`, 40) + strings.Repeat(`)`, 40)))
+		assert.NoError(t, err)
+	})
+	t.Run(`context`, func(t *testing.T) {
+		//nested backtracks twice on every level
+		var nested Rule
+		nested = Choice(
+			Seq(Literal(`(`), Ref(&nested), Literal(`)x`)),
+			Seq(Literal(`(`), Ref(&nested), Literal(`)y`)),
+			Literal(`z`),
+		)
+		input := []byte(strings.Repeat(`(`, 40) + `z` + strings.Repeat(`)y`, 40))
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err := parser.New(nested.Processor()).ParseContext(ctx, input)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), `%v`, err)
+		assert.True(t, time.Since(start) < time.Second, `%s`, time.Since(start))
+	})
+}
@@ -0,0 +1,76 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser
+
+import "unicode/utf8"
+
+type (
+	rule struct {
+		processor Processor
+		//leading marks first bytes of data processor can match, nil means any byte
+		leading *[256]bool
+	}
+	processorSet struct {
+		rules []*rule
+		table [256][]*rule
+	}
+)
+
+//AddProcessorFor adds processors which match only data starting with one of leading runes,
+//they are skipped without a call for any other data. Bytes of leading which are not valid UTF-8 are matched as they are.
+func (p *parser) AddProcessorFor(leading string, processors ...Processor) {
+	p.processors.add(leadingBytes(leading), processors...)
+}
+
+func (set *processorSet) add(leading *[256]bool, processors ...Processor) {
+	for _, processor := range processors {
+		set.rules = append(set.rules, &rule{processor: processor, leading: leading})
+	}
+	var table [256][]*rule
+	for index := range table {
+		for _, r := range set.rules {
+			if r.leading == nil || r.leading[index] {
+				table[index] = append(table[index], r)
+			}
+		}
+	}
+	set.table = table
+}
+
+//candidates returns rules which may match data starting with b in order of adding
+func (set *processorSet) candidates(b byte) []*rule {
+	return set.table[b]
+}
+
+func leadingBytes(leading string) *[256]bool {
+	result := &[256]bool{}
+	for index := 0; index < len(leading); {
+		result[leading[index]] = true
+		_, size := utf8.DecodeRuneInString(leading[index:])
+		index += size
+	}
+	return result
+}
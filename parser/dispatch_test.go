@@ -0,0 +1,101 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser_test
+
+import (
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParser_AddProcessorFor(t *testing.T) {
+	t.Run(`skipped`, func(t *testing.T) {
+		calls := 0
+		counting := func(node ast.ParentNode, data []byte, parser func(ast.ParentNode, []byte) error) (int, error) {
+			calls++
+			return 0, nil
+		}
+		p := parser.New()
+		p.AddProcessorFor(`x'`, counting)
+		_, err := p.Parse([]byte(`abc x 'd'`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, 3, calls)
+	})
+	t.Run(`same tree`, func(t *testing.T) {
+		input := []byte(`a 'b "c" d' «e» f`)
+		guillemets := parser.ProcessorByRune('«', '»', func() ast.ParentNode {
+			return &quote{Container: ast.NewContainer()}
+		})
+		expected, err := parser.New(processorQuote(), processorDoubleQuote(), guillemets).Parse(input)
+		if !assert.NoError(t, err) {
+			return
+		}
+		p := parser.New()
+		p.AddProcessorFor(`'`, processorQuote())
+		p.AddProcessorFor(`"`, processorDoubleQuote())
+		p.AddProcessorFor(`«`, guillemets)
+		actual, err := p.Parse(input)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, expected, actual)
+	})
+	t.Run(`order`, func(t *testing.T) {
+		var order []string
+		named := func(name string) parser.Processor {
+			return func(node ast.ParentNode, data []byte, parser func(ast.ParentNode, []byte) error) (int, error) {
+				order = append(order, name)
+				return 0, nil
+			}
+		}
+		p := parser.New(named(`first`))
+		p.AddProcessorFor(`a`, named(`second`))
+		p.AddProcessor(named(`third`))
+		p.AddProcessorFor(`ab`, named(`fourth`))
+		_, err := p.Parse([]byte(`ab`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []string{`first`, `second`, `third`, `fourth`, `first`, `third`, `fourth`}, order)
+	})
+	t.Run(`invalid utf8`, func(t *testing.T) {
+		var leading []byte
+		recording := func(node ast.ParentNode, data []byte, parser func(ast.ParentNode, []byte) error) (int, error) {
+			leading = append(leading, data[0])
+			return 0, nil
+		}
+		p := parser.New()
+		p.AddProcessorFor("\xff\xc3é", recording)
+		_, err := p.Parse([]byte("a\xff\xef\xc3x\xc3\xa9"))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, []byte{0xff, 0xc3, 0xc3}, leading)
+	})
+}
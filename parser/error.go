@@ -0,0 +1,120 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"github.com/biodebox/yaastr/ast"
+	"sort"
+)
+
+type (
+	Error struct {
+		ast.Position
+		Path []ast.ParentNode
+		//Processor is nil when error is not returned by processor
+		Processor Processor
+		//Expected lists input expected at Position when error is *ExpectedError
+		Expected []string
+		Err      error
+	}
+	ErrorList []*Error
+)
+
+//Error
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %v", e.Line, e.Column, e.Err)
+}
+
+//Unwrap
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+//Add
+func (l *ErrorList) Add(err *Error) {
+	*l = append(*l, err)
+}
+
+//Len
+func (l ErrorList) Len() int {
+	return len(l)
+}
+
+//Swap
+func (l ErrorList) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+
+//Less
+func (l ErrorList) Less(i, j int) bool {
+	return l[i].Offset < l[j].Offset
+}
+
+//Sort sorts errors by offset
+func (l ErrorList) Sort() {
+	sort.Stable(l)
+}
+
+//Error
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+//Err returns nil for empty list
+func (l ErrorList) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+//Is reports whether any error in list matches target
+func (l ErrorList) Is(target error) bool {
+	for _, err := range l {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+//As finds first error in list that matches target
+func (l ErrorList) As(target interface{}) bool {
+	for _, err := range l {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
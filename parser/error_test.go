@@ -0,0 +1,138 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser_test
+
+import (
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var errBang = errors.New(`bang`)
+
+func TestError(t *testing.T) {
+	t.Run(`top level`, func(t *testing.T) {
+		node, err := parser.New(processorBang()).Parse([]byte("text\nte!xt"))
+		if !assert.Error(t, err) {
+			return
+		}
+		assert.True(t, errors.Is(err, errBang))
+		var parseError *parser.Error
+		if !assert.True(t, errors.As(err, &parseError)) {
+			return
+		}
+		assert.Equal(t, ast.Position{Offset: 7, Line: 2, Column: 3}, parseError.Position)
+		assert.Equal(t, []ast.ParentNode{node.(ast.ParentNode)}, parseError.Path)
+		assert.NotNil(t, parseError.Processor)
+		assert.Equal(t, `2:3: bang`, err.Error())
+	})
+	t.Run(`nested`, func(t *testing.T) {
+		node, err := parser.New(processorQuote(), processorBang()).Parse([]byte(`text 'quo!te'`))
+		if !assert.Error(t, err) {
+			return
+		}
+		var parseError *parser.Error
+		if !assert.True(t, errors.As(err, &parseError)) {
+			return
+		}
+		assert.Equal(t, 9, parseError.Offset)
+		doc := node.(ast.ParentNode)
+		if !assert.Len(t, parseError.Path, 2) {
+			return
+		}
+		assert.Equal(t, doc, parseError.Path[0])
+		assert.IsType(t, &quote{}, parseError.Path[1])
+	})
+}
+
+func TestExpected(t *testing.T) {
+	//expecting fails at ! with expectations recorded by earlier calls at the same offset
+	expecting := func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, error) {
+		switch data[0] {
+		case '!':
+			return 0, parser.Expected(parse, data)
+		case 'a':
+			parser.Expect(parse, data[1:], `"b"`)
+			parser.Expect(parse, data[1:], `"c"`)
+			parser.Expect(parse, data[1:], `"b"`)
+			parser.Expect(parse, data, `"a"`)
+			parser.Expect(parser.Quiet(parse), data[1:], `"d"`)
+		}
+		return 0, nil
+	}
+	_, err := parser.New(expecting).Parse([]byte(`xa!`))
+	var parseError *parser.Error
+	if !assert.True(t, errors.As(err, &parseError)) {
+		return
+	}
+	assert.Equal(t, 2, parseError.Offset)
+	assert.Equal(t, []string{`"b"`, `"c"`}, parseError.Expected)
+	assert.EqualError(t, err, `1:3: expected "b" or "c"`)
+	_, err = parser.New(expecting).Parse([]byte(`!a`))
+	assert.NoError(t, err)
+	assert.EqualError(t, &parser.ExpectedError{Expected: []string{`x`}}, `expected x`)
+	assert.EqualError(t, &parser.ExpectedError{}, `unexpected input`)
+}
+
+func TestErrorList(t *testing.T) {
+	first := &parser.Error{Position: ast.Position{Offset: 10, Line: 2, Column: 1}, Err: errBang}
+	second := &parser.Error{Position: ast.Position{Offset: 1, Line: 1, Column: 2}, Err: errors.New(`boom`)}
+	t.Run(`empty`, func(t *testing.T) {
+		var list parser.ErrorList
+		assert.NoError(t, list.Err())
+	})
+	t.Run(`sort`, func(t *testing.T) {
+		var list parser.ErrorList
+		list.Add(first)
+		list.Add(second)
+		list.Sort()
+		assert.Equal(t, parser.ErrorList{second, first}, list)
+		assert.Equal(t, `1:2: boom (and 1 more errors)`, list.Error())
+	})
+	t.Run(`chain`, func(t *testing.T) {
+		var list parser.ErrorList
+		list.Add(second)
+		list.Add(first)
+		err := list.Err()
+		assert.True(t, errors.Is(err, errBang))
+		var parseError *parser.Error
+		if !assert.True(t, errors.As(err, &parseError)) {
+			return
+		}
+		assert.Equal(t, second, parseError)
+	})
+}
+
+func processorBang() parser.Processor {
+	return func(node ast.ParentNode, data []byte, parser func(ast.ParentNode, []byte) error) (int, error) {
+		if data[0] == '!' {
+			return 0, errBang
+		}
+		return 0, nil
+	}
+}
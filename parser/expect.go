@@ -0,0 +1,108 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser
+
+import (
+	"fmt"
+	"github.com/biodebox/yaastr/ast"
+	"strings"
+)
+
+type (
+	//ExpectedError lists input which was expected at the farthest offset where matching failed, see Expect
+	ExpectedError struct {
+		Offset   int
+		Expected []string
+	}
+	expectation struct {
+		ast.ParentNode
+		expected string
+	}
+	expectations struct {
+		ast.ParentNode
+		err *ExpectedError
+	}
+)
+
+//Expect records that expected was not found at the beginning of data. Parser keeps expectations of the farthest
+//offset only, they are returned by Expected.
+func Expect(parser func(ast.ParentNode, []byte) error, data []byte, expected string) {
+	_ = parser(&expectation{expected: expected}, data)
+}
+
+//Expected returns *ExpectedError for expectations recorded at or after data, it returns nil when there are none
+func Expected(parser func(ast.ParentNode, []byte) error, data []byte) error {
+	e := &expectations{}
+	if err := parser(e, data); err != nil || e.err == nil {
+		return nil
+	}
+	return e.err
+}
+
+//Quiet wraps parser callback so Expect calls are ignored, it is used for predicates which failures are not expected input
+func Quiet(parser func(ast.ParentNode, []byte) error) func(ast.ParentNode, []byte) error {
+	return func(node ast.ParentNode, data []byte) error {
+		if _, ok := node.(*expectation); ok {
+			return nil
+		}
+		return parser(node, data)
+	}
+}
+
+//Error
+func (e *ExpectedError) Error() string {
+	switch len(e.Expected) {
+	case 0:
+		return "unexpected input"
+	case 1:
+		return "expected " + e.Expected[0]
+	}
+	last := len(e.Expected) - 1
+	return fmt.Sprintf("expected %s or %s", strings.Join(e.Expected[:last], ", "), e.Expected[last])
+}
+
+//expect adds expected to expectations of offset when it is not before the farthest one
+func (s *state) expect(offset int, expected string) {
+	switch {
+	case s.expected == nil || offset > s.expected.Offset:
+		s.expected = &ExpectedError{Offset: offset, Expected: []string{expected}}
+	case offset == s.expected.Offset:
+		for _, e := range s.expected.Expected {
+			if e == expected {
+				return
+			}
+		}
+		s.expected.Expected = append(s.expected.Expected, expected)
+	}
+}
+
+//expectedFrom returns copy of the farthest expectations when they are not before offset
+func (s *state) expectedFrom(offset int) *ExpectedError {
+	if s.expected == nil || s.expected.Offset < offset {
+		return nil
+	}
+	return &ExpectedError{Offset: s.expected.Offset, Expected: append([]string(nil), s.expected.Expected...)}
+}
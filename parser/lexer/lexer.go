@@ -0,0 +1,141 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+//Package lexer splits input into tokens by rules, parser processes the tokens in token mode, see parser.Tokenize
+package lexer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/biodebox/yaastr/ast"
+)
+
+type (
+	//Kind identifies tokens of a rule, values are defined by user
+	Kind  int
+	Token struct {
+		Kind Kind
+		//Bytes is a sub-slice of input
+		Bytes []byte
+		Span  ast.Span
+	}
+	//Matcher returns length of token at the beginning of data or 0 for no match,
+	//when more is set and result may change with input after data it returns Incomplete.
+	Matcher func(data []byte, more bool) int
+	Rule    struct {
+		kind  Kind
+		match Matcher
+		skip  bool
+	}
+	Lexer struct {
+		rules []Rule
+	}
+	Error struct {
+		ast.Position
+		Byte byte
+	}
+)
+
+//Incomplete is returned by matchers which need input after the end of data
+const Incomplete = -1
+
+//ErrUnexpected is wrapped by errors of input which no rule matches
+var ErrUnexpected = errors.New("unexpected input")
+
+//Define makes tokens of kind for input matched by match
+func Define(kind Kind, match Matcher) Rule {
+	return Rule{kind: kind, match: match}
+}
+
+//Skip matches input which gets no token, like whitespace or comments, parser.Tokenize keeps it in text nodes
+func Skip(match Matcher) Rule {
+	return Rule{match: match, skip: true}
+}
+
+//New creates lexer which takes the longest match of rules, the first rule wins when matches have the same length
+func New(rules ...Rule) *Lexer {
+	return &Lexer{rules: rules}
+}
+
+//Error
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %v %q", e.Line, e.Column, ErrUnexpected, e.Byte)
+}
+
+//Unwrap
+func (e *Error) Unwrap() error {
+	return ErrUnexpected
+}
+
+//Tokenize splits whole data into tokens, it fails on input which no rule matches
+func (l *Lexer) Tokenize(data []byte) ([]Token, error) {
+	tokens, n := l.Scan(nil, data, 0, true)
+	if n < len(data) {
+		return tokens, &Error{Position: ast.PositionOf(data, n), Byte: data[n]}
+	}
+	return tokens, nil
+}
+
+//Scan appends tokens of data to tokens, base is offset of data in input. It stops before input which no rule matches
+//and, unless eof is set, before token which may continue after data. It returns length of scanned data.
+func (l *Lexer) Scan(tokens []Token, data []byte, base int, eof bool) ([]Token, int) {
+	offset := 0
+	for offset < len(data) {
+		rule, n := l.match(data[offset:], eof)
+		if n <= 0 {
+			return tokens, offset
+		}
+		if !rule.skip {
+			tokens = append(tokens, token(rule.kind, data, offset, n, base))
+		}
+		offset += n
+	}
+	return tokens, offset
+}
+
+//match returns the longest match of rules
+func (l *Lexer) match(data []byte, eof bool) (*Rule, int) {
+	var longest *Rule
+	length := 0
+	for index := range l.rules {
+		rule := &l.rules[index]
+		n := rule.match(data, !eof)
+		if n == Incomplete {
+			return nil, Incomplete
+		}
+		if n > length {
+			longest, length = rule, n
+		}
+	}
+	return longest, length
+}
+
+func token(kind Kind, data []byte, offset, n, base int) Token {
+	return Token{
+		Kind:  kind,
+		Bytes: data[offset : offset+n],
+		Span:  ast.Span{Start: base + offset, End: base + offset + n},
+	}
+}
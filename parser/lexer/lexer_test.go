@@ -0,0 +1,127 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package lexer_test
+
+import (
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	. "github.com/biodebox/yaastr/parser/lexer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const (
+	kindIdentifier Kind = iota + 1
+	kindNumber
+	kindOperator
+	kindKeyword
+)
+
+func newLexer() *Lexer {
+	return New(
+		Skip(Space()),
+		Skip(Regexp(`#[^\n]*\n`)),
+		Define(kindKeyword, Literal("let")),
+		Define(kindIdentifier, Identifier()),
+		Define(kindNumber, Number()),
+		Define(kindOperator, Literal("=")),
+		Define(kindOperator, Literal("==")),
+	)
+}
+
+func TestLexer_Tokenize(t *testing.T) {
+	data := []byte("let x == 1.5 # comment\nletter = 2")
+	tokens, err := newLexer().Tokenize(data)
+	if !assert.NoError(t, err) {
+		return
+	}
+	type result struct {
+		kind  Kind
+		text  string
+		start int
+	}
+	var actual []result
+	for _, token := range tokens {
+		assert.Equal(t, string(data[token.Span.Start:token.Span.End]), string(token.Bytes))
+		actual = append(actual, result{kind: token.Kind, text: string(token.Bytes), start: token.Span.Start})
+	}
+	assert.Equal(t, []result{
+		{kind: kindKeyword, text: "let", start: 0},
+		{kind: kindIdentifier, text: "x", start: 4},
+		{kind: kindOperator, text: "==", start: 6},
+		{kind: kindNumber, text: "1.5", start: 9},
+		{kind: kindIdentifier, text: "letter", start: 23},
+		{kind: kindOperator, text: "=", start: 30},
+		{kind: kindNumber, text: "2", start: 32},
+	}, actual)
+	_, err = newLexer().Tokenize([]byte("x = 1\ny ? 2"))
+	var lexerError *Error
+	if assert.True(t, errors.As(err, &lexerError)) {
+		assert.Equal(t, &Error{Position: ast.Position{Offset: 8, Line: 2, Column: 3}, Byte: '?'}, lexerError)
+		assert.True(t, errors.Is(err, ErrUnexpected))
+		assert.Equal(t, `2:3: unexpected input '?'`, err.Error())
+	}
+}
+
+func TestLexer_Scan(t *testing.T) {
+	l := newLexer()
+	tests := []struct {
+		name   string
+		data   string
+		eof    bool
+		texts  []string
+		length int
+	}{
+		{name: `complete`, data: "a = 1 b", texts: []string{"a", "=", "1"}, length: 6},
+		{name: `identifier at the end`, data: "a = bc", texts: []string{"a", "="}, length: 4},
+		{name: `identifier at eof`, data: "a = bc", eof: true, texts: []string{"a", "=", "bc"}, length: 6},
+		{name: `prefix of operator`, data: "a =", texts: []string{"a"}, length: 2},
+		{name: `fraction`, data: "1.", texts: nil, length: 0},
+		{name: `number at eof`, data: "1.", eof: true, texts: []string{"1"}, length: 1},
+		{name: `unexpected`, data: "a ? b", eof: true, texts: []string{"a"}, length: 2},
+		{name: `utf8`, data: "щ \xd1", texts: []string{"щ"}, length: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens, length := l.Scan(nil, []byte(test.data), 10, test.eof)
+			var texts []string
+			for _, token := range tokens {
+				texts = append(texts, string(token.Bytes))
+			}
+			assert.Equal(t, test.texts, texts)
+			assert.Equal(t, test.length, length)
+			if len(tokens) > 0 {
+				assert.Equal(t, 10, tokens[0].Span.Start)
+			}
+		})
+	}
+}
+
+func TestLiteral_Empty(t *testing.T) {
+	assert.Panics(t, func() {
+		Literal("")
+	})
+}
@@ -0,0 +1,130 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package lexer
+
+import (
+	"bytes"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+//Literal matches literal string
+func Literal(literal string) Matcher {
+	if len(literal) == 0 {
+		panic("yaastr: empty literal")
+	}
+	value := []byte(literal)
+	return func(data []byte, more bool) int {
+		if bytes.HasPrefix(data, value) {
+			return len(value)
+		}
+		if more && len(data) < len(value) && bytes.HasPrefix(value, data) {
+			return Incomplete
+		}
+		return 0
+	}
+}
+
+//Regexp matches pattern at the beginning of data, match which reaches the end of data is incomplete when more input may follow.
+//No match is final, so pattern must not need input after data to start matching.
+func Regexp(pattern string) Matcher {
+	re := regexp.MustCompile(`^(?:` + pattern + `)`)
+	return func(data []byte, more bool) int {
+		location := re.FindIndex(data)
+		switch {
+		case location == nil:
+			return 0
+		case more && location[1] == len(data):
+			return Incomplete
+		}
+		return location[1]
+	}
+}
+
+//Space matches unicode white space
+func Space() Matcher {
+	return Runes(unicode.IsSpace, unicode.IsSpace)
+}
+
+//Identifier matches letter or underscore followed by letters, digits and underscores
+func Identifier() Matcher {
+	return Runes(isIdentifierStart, isIdentifierPart)
+}
+
+//Number matches decimal digits with optional fraction like 12 or 1.5
+func Number() Matcher {
+	digits := Runes(isDigit, isDigit)
+	return func(data []byte, more bool) int {
+		n := digits(data, more)
+		if n <= 0 || n == len(data) || data[n] != '.' {
+			return n
+		}
+		fraction := digits(data[n+1:], more)
+		switch {
+		case fraction == Incomplete || more && n+1 == len(data):
+			return Incomplete
+		case fraction == 0:
+			return n
+		}
+		return n + 1 + fraction
+	}
+}
+
+//Runes matches rune accepted by first followed by runes accepted by next
+func Runes(first, next func(rune) bool) Matcher {
+	return func(data []byte, more bool) int {
+		accept := first
+		offset := 0
+		for offset < len(data) {
+			if more && !utf8.FullRune(data[offset:]) {
+				return Incomplete
+			}
+			r, size := utf8.DecodeRune(data[offset:])
+			if !accept(r) {
+				return offset
+			}
+			accept = next
+			offset += size
+		}
+		if more {
+			return Incomplete
+		}
+		return offset
+	}
+}
+
+func isIdentifierStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentifierPart(r rune) bool {
+	return isIdentifierStart(r) || unicode.IsDigit(r)
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
@@ -0,0 +1,162 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser
+
+import (
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"sync/atomic"
+)
+
+type (
+	//MemoStats counts lookups of memo table, see Memo option
+	MemoStats struct {
+		Hits   int64
+		Misses int64
+	}
+	memoKey struct {
+		key        interface{}
+		start, end int
+		//mode is processors of active mode, nested parses of the same input differ between modes
+		mode *processorSet
+	}
+	memoEntry struct {
+		n     int
+		ok    bool
+		err   error
+		nodes []ast.Node
+		//count is number of nodes counted during match
+		count int
+		//expected is the farthest expectation after match, it is recorded again on hit
+		expected *ExpectedError
+	}
+	//memoCheck asks parser callback whether memo table is used, so Memoize allocates nothing without it
+	memoCheck struct {
+		ast.ParentNode
+	}
+	memoizer struct {
+		ast.ParentNode
+		key     interface{}
+		match   func(ast.ParentNode, []byte, func(ast.ParentNode, []byte) error) (int, bool, error)
+		handled bool
+		n       int
+		ok      bool
+		err     error
+	}
+)
+
+var (
+	memoEnabled = &memoCheck{}
+	errMemo     = errors.New("memo")
+)
+
+//Memoize runs match for node and data once per key and position of data when parser has Memo option,
+//nodes appended by match are cloned with ast.Clone for repeated calls, results with nodes which ast.CanClone rejects
+//are not cached. Without the option match is always run.
+func Memoize(
+	parser func(ast.ParentNode, []byte) error,
+	key interface{},
+	node ast.ParentNode,
+	data []byte,
+	match func(ast.ParentNode, []byte, func(ast.ParentNode, []byte) error) (int, bool, error),
+) (int, bool, error) {
+	if parser(memoEnabled, data) != errMemo {
+		return match(node, data, parser)
+	}
+	m := &memoizer{ParentNode: node, key: key, match: match}
+	if err := parser(m, data); err != nil || !m.handled {
+		return match(node, data, parser)
+	}
+	return m.n, m.ok, m.err
+}
+
+//HitRate returns share of lookups found in memo table
+func (m *MemoStats) HitRate() float64 {
+	if m.Hits+m.Misses == 0 {
+		return 0
+	}
+	return float64(m.Hits) / float64(m.Hits+m.Misses)
+}
+
+//run calls processor of rule, results are memoized in nested parses
+func (s *state) run(r *rule, node ast.ParentNode, data []byte) (int, error) {
+	if s.memo == nil || len(s.path) < 2 {
+		return r.processor(node, data, s.nested)
+	}
+	n, _, err := s.memoize(r, node, data, func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		n, err := r.processor(node, data, parse)
+		return n, true, err
+	})
+	return n, err
+}
+
+func (s *state) memoize(
+	key interface{},
+	node ast.ParentNode,
+	data []byte,
+	match func(ast.ParentNode, []byte, func(ast.ParentNode, []byte) error) (int, bool, error),
+) (int, bool, error) {
+	start := s.offset(data)
+	k := memoKey{key: key, start: start, end: start + len(data), mode: s.current()}
+	if entry, ok := s.memo[k]; ok {
+		s.hits++
+		for _, child := range entry.nodes {
+			node.AppendNode(ast.Clone(child))
+		}
+		if err := s.count(entry.count, start); err != nil {
+			return 0, false, err
+		}
+		if entry.expected != nil {
+			for _, expected := range entry.expected.Expected {
+				s.expect(entry.expected.Offset, expected)
+			}
+		}
+		return entry.n, entry.ok, entry.err
+	}
+	s.misses++
+	index, nodes := len(node.GetChildren()), s.nodes
+	n, ok, err := match(node, data, s.nested)
+	entry := &memoEntry{n: n, ok: ok, err: err, count: s.nodes - nodes, expected: s.expectedFrom(start)}
+	if ok && err == nil {
+		entry.nodes = append([]ast.Node(nil), node.GetChildren()[index:]...)
+		for _, child := range entry.nodes {
+			//nodes which can not be cloned are created again by match
+			if !ast.CanClone(child) {
+				return n, ok, err
+			}
+		}
+	}
+	s.memo[k] = entry
+	return n, ok, err
+}
+
+//finish adds memo counters to stats
+func (s *state) finish() {
+	if s.memoStats != nil {
+		atomic.AddInt64(&s.memoStats.Hits, s.hits)
+		atomic.AddInt64(&s.memoStats.Misses, s.misses)
+	}
+}
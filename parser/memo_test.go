@@ -0,0 +1,191 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"testing/iotest"
+)
+
+//processorsSpeculative returns processors which parse content of parentheses twice, the first parse is discarded
+func processorsSpeculative(calls *int) []parser.Processor {
+	quote := processorQuote()
+	return []parser.Processor{
+		func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, error) {
+			end := bytes.IndexByte(data, ')')
+			if data[0] != '(' || end < 0 {
+				return 0, nil
+			}
+			return 0, parse(ast.NewContainer(), data[1:end])
+		},
+		parser.ProcessorByRune('(', ')', func() ast.ParentNode {
+			return ast.NewContainer()
+		}),
+		func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, error) {
+			*calls++
+			return quote(node, data, parse)
+		},
+	}
+}
+
+func TestMemo(t *testing.T) {
+	input := []byte(`text ('a' b 'c') ('d')`)
+	calls := 0
+	expected, err := parser.New(processorsSpeculative(&calls)...).Parse(input)
+	if !assert.NoError(t, err) {
+		return
+	}
+	t.Run(`processors`, func(t *testing.T) {
+		memoCalls := 0
+		stats := &parser.MemoStats{}
+		p := parser.New(processorsSpeculative(&memoCalls)...)
+		p.SetOptions(parser.Memo(stats))
+		actual, err := p.Parse(input)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, expected, actual)
+		assert.True(t, memoCalls < calls)
+		assert.Equal(t, parser.MemoStats{Hits: 18, Misses: 27}, *stats)
+		assert.Equal(t, 0.4, stats.HitRate())
+		_, err = p.Parse(input)
+		if assert.NoError(t, err) {
+			assert.Equal(t, parser.MemoStats{Hits: 36, Misses: 54}, *stats)
+		}
+	})
+	t.Run(`reader`, func(t *testing.T) {
+		p := parser.New(processorsSpeculative(new(int))...)
+		p.SetOptions(parser.Memo(nil))
+		actual, err := p.ParseReader(iotest.OneByteReader(bytes.NewReader(input)))
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected, actual)
+		}
+	})
+	t.Run(`max nodes`, func(t *testing.T) {
+		p := parser.New(processorsSpeculative(new(int))...)
+		p.SetOptions(parser.Memo(nil), parser.MaxNodes(17))
+		_, err := p.Parse(input)
+		assert.True(t, errors.Is(err, parser.ErrMaxNodes))
+		p.SetOptions(parser.MaxNodes(18))
+		_, err = p.Parse(input)
+		assert.NoError(t, err)
+	})
+	t.Run(`not cloneable`, func(t *testing.T) {
+		type plain struct {
+			*ast.Container
+		}
+		processors := func() []parser.Processor {
+			return []parser.Processor{
+				func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, error) {
+					end := bytes.IndexByte(data, ')')
+					if data[0] != '(' || end < 0 {
+						return 0, nil
+					}
+					return 0, parse(ast.NewContainer(), data[1:end])
+				},
+				parser.ProcessorByRune('(', ')', func() ast.ParentNode {
+					return &plain{Container: ast.NewContainer()}
+				}),
+				parser.ProcessorByRune('\'', '\'', func() ast.ParentNode {
+					return &plain{Container: ast.NewContainer()}
+				}),
+			}
+		}
+		expected, err := parser.New(processors()...).Parse(input)
+		if !assert.NoError(t, err) {
+			return
+		}
+		p := parser.New(processors()...)
+		p.SetOptions(parser.Memo(nil))
+		actual, err := p.Parse(input)
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected, actual)
+		}
+	})
+	t.Run(`empty stats`, func(t *testing.T) {
+		assert.Equal(t, 0.0, (&parser.MemoStats{}).HitRate())
+	})
+}
+
+func TestMemoize(t *testing.T) {
+	calls := 0
+	match := func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		calls++
+		node.AppendNode(ast.NewText(data[:1]...))
+		return 1, true, nil
+	}
+	key := new(int)
+	memoized := func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, error) {
+		if data[0] != '!' {
+			return 0, nil
+		}
+		for i := 0; i < 3; i++ {
+			if _, _, err := parser.Memoize(parse, key, node, data, match); err != nil {
+				return 0, err
+			}
+		}
+		return 1, nil
+	}
+	t.Run(`memo`, func(t *testing.T) {
+		calls = 0
+		stats := &parser.MemoStats{}
+		p := parser.New(memoized)
+		p.SetOptions(parser.Memo(stats))
+		node, err := p.Parse([]byte(`a!b!`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, parser.MemoStats{Hits: 4, Misses: 2}, *stats)
+		children := node.(*ast.Document).Children
+		if assert.Len(t, children, 8) {
+			assert.Equal(t, children[1], children[2])
+			assert.False(t, children[1] == children[2])
+		}
+	})
+	t.Run(`without memo`, func(t *testing.T) {
+		calls = 0
+		_, err := parser.New(memoized).Parse([]byte(`a!b!`))
+		if assert.NoError(t, err) {
+			assert.Equal(t, 6, calls)
+		}
+	})
+	t.Run(`foreign callback`, func(t *testing.T) {
+		calls = 0
+		n, ok, err := parser.Memoize(func(ast.ParentNode, []byte) error {
+			return nil
+		}, key, ast.NewContainer(), []byte(`!`), match)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.True(t, ok)
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -3,6 +3,8 @@
 package mocks
 
 import ast "github.com/biodebox/yaastr/ast"
+import context "context"
+import io "io"
 import mock "github.com/stretchr/testify/mock"
 import parser "github.com/biodebox/yaastr/parser"
 
@@ -11,6 +13,30 @@ type Parser struct {
 	mock.Mock
 }
 
+// AddMode provides a mock function with given fields: name, processors
+func (_m *Parser) AddMode(name string, processors ...parser.Processor) {
+	_va := make([]interface{}, len(processors))
+	for _i := range processors {
+		_va[_i] = processors[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, name)
+	_ca = append(_ca, _va...)
+	_m.Called(_ca...)
+}
+
+// AddModeFor provides a mock function with given fields: name, leading, processors
+func (_m *Parser) AddModeFor(name string, leading string, processors ...parser.Processor) {
+	_va := make([]interface{}, len(processors))
+	for _i := range processors {
+		_va[_i] = processors[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, name, leading)
+	_ca = append(_ca, _va...)
+	_m.Called(_ca...)
+}
+
 // AddProcessor provides a mock function with given fields: processors
 func (_m *Parser) AddProcessor(processors ...parser.Processor) {
 	_va := make([]interface{}, len(processors))
@@ -22,6 +48,18 @@ func (_m *Parser) AddProcessor(processors ...parser.Processor) {
 	_m.Called(_ca...)
 }
 
+// AddProcessorFor provides a mock function with given fields: leading, processors
+func (_m *Parser) AddProcessorFor(leading string, processors ...parser.Processor) {
+	_va := make([]interface{}, len(processors))
+	for _i := range processors {
+		_va[_i] = processors[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, leading)
+	_ca = append(_ca, _va...)
+	_m.Called(_ca...)
+}
+
 // Parse provides a mock function with given fields: _a0
 func (_m *Parser) Parse(_a0 []byte) (ast.Node, error) {
 	ret := _m.Called(_a0)
@@ -44,3 +82,83 @@ func (_m *Parser) Parse(_a0 []byte) (ast.Node, error) {
 
 	return r0, r1
 }
+
+// ParseContext provides a mock function with given fields: _a0, _a1
+func (_m *Parser) ParseContext(_a0 context.Context, _a1 []byte) (ast.Node, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 ast.Node
+	if rf, ok := ret.Get(0).(func(context.Context, []byte) ast.Node); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(ast.Node)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []byte) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ParseReader provides a mock function with given fields: _a0
+func (_m *Parser) ParseReader(_a0 io.Reader) (ast.Node, error) {
+	ret := _m.Called(_a0)
+
+	var r0 ast.Node
+	if rf, ok := ret.Get(0).(func(io.Reader) ast.Node); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(ast.Node)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(io.Reader) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Reparse provides a mock function with given fields: old, data, edit
+func (_m *Parser) Reparse(old ast.Node, data []byte, edit parser.Edit) (ast.Node, error) {
+	ret := _m.Called(old, data, edit)
+
+	var r0 ast.Node
+	if rf, ok := ret.Get(0).(func(ast.Node, []byte, parser.Edit) ast.Node); ok {
+		r0 = rf(old, data, edit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(ast.Node)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(ast.Node, []byte, parser.Edit) error); ok {
+		r1 = rf(old, data, edit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetOptions provides a mock function with given fields: options
+func (_m *Parser) SetOptions(options ...parser.Option) {
+	_va := make([]interface{}, len(options))
+	for _i := range options {
+		_va[_i] = options[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _va...)
+	_m.Called(_ca...)
+}
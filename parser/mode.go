@@ -0,0 +1,115 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"github.com/biodebox/yaastr/ast"
+)
+
+type (
+	//modeFrame is an element of mode stack, nil frame is the default mode
+	modeFrame struct {
+		set   *processorSet
+		outer *modeFrame
+	}
+	modeSwitch struct {
+		ast.ParentNode
+		name string
+		pop  bool
+	}
+)
+
+var ErrUnknownMode = errors.New("unknown mode")
+
+//AddMode adds processors to named set, it is used for content of nodes wrapped with PushMode.
+//Mode with empty name is the default set of AddProcessor.
+func (p *parser) AddMode(name string, processors ...Processor) {
+	p.modeSet(name).add(nil, processors...)
+}
+
+//AddModeFor works like AddProcessorFor for named set
+func (p *parser) AddModeFor(name, leading string, processors ...Processor) {
+	p.modeSet(name).add(leadingBytes(leading), processors...)
+}
+
+//PushMode wraps node for the nested parser callback, content is parsed by processors of named mode.
+//The mode stays for deeper nested parses until they push another one, unknown name fails with ErrUnknownMode.
+func PushMode(name string, node ast.ParentNode) ast.ParentNode {
+	return &modeSwitch{ParentNode: node, name: name}
+}
+
+//PopMode wraps node for the nested parser callback, content is parsed by processors of mode which was active
+//before the current one was pushed. Popping the default mode keeps it.
+func PopMode(node ast.ParentNode) ast.ParentNode {
+	return &modeSwitch{ParentNode: node, pop: true}
+}
+
+func (p *parser) modeSet(name string) *processorSet {
+	if name == "" {
+		return &p.processors
+	}
+	if p.modes == nil {
+		p.modes = make(map[string]*processorSet)
+	}
+	set, ok := p.modes[name]
+	if !ok {
+		set = &processorSet{}
+		p.modes[name] = set
+	}
+	return set
+}
+
+//switchMode parses wrapped node with changed mode and restores the mode after
+func (s *state) switchMode(n *modeSwitch, data []byte) error {
+	outer := s.mode
+	defer func() {
+		s.mode = outer
+	}()
+	switch {
+	case n.pop && outer != nil:
+		s.mode = outer.outer
+	case n.pop:
+	case n.name == "":
+		s.mode = &modeFrame{set: &s.processors, outer: outer}
+	default:
+		set, ok := s.modes[n.name]
+		if !ok {
+			return s.error(s.offset(data), nil, fmt.Errorf("%w %q", ErrUnknownMode, n.name))
+		}
+		s.mode = &modeFrame{set: set, outer: outer}
+	}
+	return s.parse(n.ParentNode, data)
+}
+
+//current returns processors of active mode
+func (s *state) current() *processorSet {
+	if s.mode == nil {
+		return &s.processors
+	}
+	return s.mode.set
+}
@@ -0,0 +1,158 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"testing/iotest"
+)
+
+//processorTemplate parses content of {{ }} in code mode
+func processorTemplate() parser.Processor {
+	return parser.ProcessorByStrings(`{{`, `}}`, func() ast.ParentNode {
+		return ast.NewContainer()
+	}, parser.Nested(), parser.InMode(`code`))
+}
+
+//processorBacktick parses content of backticks in mode which was active before code
+func processorBacktick() parser.Processor {
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, error) {
+		if data[0] != '`' {
+			return 0, nil
+		}
+		end := bytes.IndexByte(data[1:], '`')
+		if end < 0 {
+			return 0, nil
+		}
+		q := &quote{Container: ast.NewContainer(), double: true}
+		node.AppendNode(q)
+		return end + 2, parse(parser.PopMode(q), data[1:end+1])
+	}
+}
+
+func TestParser_AddMode(t *testing.T) {
+	data := []byte("'a' {{ 'b' `c 'd' {{'e'}}` }}")
+	newParser := func() parser.Parser {
+		p := parser.New(processorTemplate())
+		p.AddMode(`code`, processorQuote())
+		p.AddModeFor(`code`, "`", processorBacktick())
+		return p
+	}
+	expected := document(len(data))
+	inner := span(ast.NewContainer(), 18, 25).(*ast.Container)
+	inner.AppendNode(span(&quote{Container: ast.NewContainer()}, 20, 23))
+	inner.Children[0].(*quote).AppendNode(text(`e`, 21))
+	backtick := span(&quote{Container: ast.NewContainer(), double: true}, 11, 26).(*quote)
+	backtick.AppendNode(text(`c 'd' `, 12), inner)
+	b := span(&quote{Container: ast.NewContainer()}, 7, 10).(*quote)
+	b.AppendNode(text(`b`, 8))
+	code := span(ast.NewContainer(), 4, 29).(*ast.Container)
+	code.AppendNode(text(` `, 6), b, text(` `, 10), backtick, text(` `, 26))
+	expected.AppendNode(text(`'a' `, 0), code)
+	t.Run(`parse`, func(t *testing.T) {
+		node, err := newParser().Parse(data)
+		if assert.NoError(t, err) {
+			assert.True(t, ast.Equal(expected, node), ast.Diff(expected, node))
+		}
+	})
+	t.Run(`reader`, func(t *testing.T) {
+		node, err := newParser().ParseReader(iotest.OneByteReader(bytes.NewReader(data)))
+		if assert.NoError(t, err) {
+			assert.True(t, ast.Equal(expected, node), ast.Diff(expected, node))
+		}
+	})
+	t.Run(`memo`, func(t *testing.T) {
+		p := newParser()
+		p.SetOptions(parser.Memo(nil))
+		node, err := p.Parse(data)
+		if assert.NoError(t, err) {
+			assert.True(t, ast.Equal(expected, node), ast.Diff(expected, node))
+		}
+	})
+	t.Run(`push default`, func(t *testing.T) {
+		p := parser.New(processorTemplate())
+		p.AddMode(`code`, parser.ProcessorByRune('(', ')', func() ast.ParentNode {
+			return ast.NewContainer()
+		}, parser.InMode(``)))
+		node, err := p.Parse([]byte(`{{({{}})}}`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		expected := document(10)
+		paren := span(ast.NewContainer(), 2, 8).(*ast.Container)
+		paren.AppendNode(span(ast.NewContainer(), 3, 7))
+		code := span(ast.NewContainer(), 0, 10).(*ast.Container)
+		code.AppendNode(paren)
+		expected.AppendNode(code)
+		assert.True(t, ast.Equal(expected, node), ast.Diff(expected, node))
+	})
+	t.Run(`escaped content`, func(t *testing.T) {
+		factory := func() ast.ParentNode {
+			return ast.NewContainer()
+		}
+		p := parser.New(parser.ProcessorByRune('{', '}', factory, parser.InMode(`code`), parser.SkipEscaped('\\')))
+		p.AddMode(`code`, parser.ProcessorByRune('(', ')', factory))
+		node, err := p.Parse([]byte(`{a (b) \} c}`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		expected := document(12)
+		paren := span(ast.NewContainer(), 3, 6).(*ast.Container)
+		paren.AppendNode(text(`b`, 4))
+		code := span(ast.NewContainer(), 0, 12).(*ast.Container)
+		code.AppendNode(text(`a `, 1), paren, text(` \} c`, 6))
+		expected.AppendNode(code)
+		assert.True(t, ast.Equal(expected, node), ast.Diff(expected, node))
+	})
+	t.Run(`pop default`, func(t *testing.T) {
+		p := parser.New(processorBacktick(), processorQuote())
+		node, err := p.Parse([]byte("`'a'`"))
+		if !assert.NoError(t, err) {
+			return
+		}
+		expected := document(5)
+		q := span(&quote{Container: ast.NewContainer()}, 1, 4).(*quote)
+		q.AppendNode(text(`a`, 2))
+		backtick := span(&quote{Container: ast.NewContainer(), double: true}, 0, 5).(*quote)
+		backtick.AppendNode(q)
+		expected.AppendNode(backtick)
+		assert.True(t, ast.Equal(expected, node), ast.Diff(expected, node))
+	})
+	t.Run(`unknown mode`, func(t *testing.T) {
+		_, err := parser.New(processorTemplate()).Parse([]byte(`a {{b}}`))
+		var parseError *parser.Error
+		if assert.True(t, errors.As(err, &parseError)) {
+			assert.True(t, errors.Is(err, parser.ErrUnknownMode))
+			assert.Equal(t, 4, parseError.Offset)
+			assert.EqualError(t, err, `1:5: unknown mode "code"`)
+		}
+	})
+}
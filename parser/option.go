@@ -0,0 +1,104 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser
+
+import "errors"
+
+type Option func(*parser)
+
+var (
+	ErrMaxDepth     = errors.New("maximum nesting depth exceeded")
+	ErrMaxNodes     = errors.New("maximum number of nodes exceeded")
+	ErrMaxInputSize = errors.New("maximum input size exceeded")
+)
+
+//MaxDepth limits nesting of parent nodes, document has depth 1
+func MaxDepth(depth int) Option {
+	return func(p *parser) {
+		p.maxDepth = depth
+	}
+}
+
+//MaxNodes limits number of nodes created during parse, document is not counted
+func MaxNodes(nodes int) Option {
+	return func(p *parser) {
+		p.maxNodes = nodes
+	}
+}
+
+//MaxInputSize limits length of input in bytes
+func MaxInputSize(size int) Option {
+	return func(p *parser) {
+		p.maxInput = size
+	}
+}
+
+//CopyText copies content of text nodes instead of sharing memory with input
+func CopyText() Option {
+	return func(p *parser) {
+		p.copyText = true
+	}
+}
+
+//Memo caches results of processors in nested parses and of Memoize by position, counters are added to stats when it is not nil
+func Memo(stats *MemoStats) Option {
+	return func(p *parser) {
+		p.memo = true
+		p.memoStats = stats
+	}
+}
+
+//Recover keeps parsing after processor errors, input from the error up to the nearest sync point is replaced with ast.Error node.
+//Without sync points one rune is replaced. Parse returns the whole tree and ErrorList of all errors.
+func Recover(syncPoints ...string) Option {
+	return func(p *parser) {
+		p.recovery = true
+		p.syncPoints = p.syncPoints[:0]
+		for _, point := range syncPoints {
+			if len(point) > 0 {
+				p.syncPoints = append(p.syncPoints, []byte(point))
+			}
+		}
+	}
+}
+
+//Incremental keeps rules which created top level nodes of the latest tree built by the parser, so Reparse reuses nodes
+//before an edit too. Processors which create top level nodes must not read more than lookahead bytes past input they consume.
+//ProcessorByRune, ProcessorBalanced, ProcessorByStrings and ProcessorByDelimiters read nothing past the closing delimiter,
+//with EscapeByDoubling option they read length of the closing delimiter past it. Tokenize consumes the whole data.
+//Combinator and PEG rules read one rune past repetitions and any input in predicates.
+func Incremental(lookahead int) Option {
+	return func(p *parser) {
+		p.latest = &history{lookahead: lookahead}
+	}
+}
+
+//SetOptions
+func (p *parser) SetOptions(options ...Option) {
+	for _, option := range options {
+		option(p)
+	}
+}
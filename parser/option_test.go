@@ -0,0 +1,139 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser_test
+
+import (
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMaxDepth(t *testing.T) {
+	p := parser.New(processorQuote(), processorDoubleQuote())
+	p.SetOptions(parser.MaxDepth(3))
+	t.Run(`within`, func(t *testing.T) {
+		_, err := p.Parse([]byte(`'a "b" c'`))
+		assert.NoError(t, err)
+	})
+	t.Run(`exceeded`, func(t *testing.T) {
+		p := parser.New(processorQuote(), processorDoubleQuote())
+		p.SetOptions(parser.MaxDepth(2))
+		_, err := p.Parse([]byte(`'a "b" c'`))
+		assert.True(t, errors.Is(err, parser.ErrMaxDepth))
+		assert.EqualError(t, err, `1:5: maximum nesting depth exceeded`)
+	})
+	t.Run(`deep input`, func(t *testing.T) {
+		p := parser.New(parser.ProcessorBalanced('(', ')', func() ast.ParentNode {
+			return &quote{Container: ast.NewContainer()}
+		}))
+		p.SetOptions(parser.MaxDepth(100))
+		_, err := p.Parse([]byte(strings.Repeat(`(`, 10000) + strings.Repeat(`)`, 10000)))
+		assert.True(t, errors.Is(err, parser.ErrMaxDepth))
+	})
+}
+
+func TestMaxNodes(t *testing.T) {
+	p := parser.New(processorQuote())
+	p.SetOptions(parser.MaxNodes(4))
+	t.Run(`within`, func(t *testing.T) {
+		_, err := p.Parse([]byte(`a 'b' c`))
+		assert.NoError(t, err)
+	})
+	t.Run(`exceeded`, func(t *testing.T) {
+		_, err := p.Parse([]byte(`a 'b' c 'd'`))
+		assert.True(t, errors.Is(err, parser.ErrMaxNodes))
+		assert.EqualError(t, err, `1:9: maximum number of nodes exceeded`)
+	})
+	t.Run(`stream`, func(t *testing.T) {
+		_, err := p.ParseReader(strings.NewReader(`a 'b' c 'd'`))
+		assert.True(t, errors.Is(err, parser.ErrMaxNodes))
+	})
+	t.Run(`literal`, func(t *testing.T) {
+		p := parser.New(processorQuote(parser.Verbatim()))
+		p.SetOptions(parser.MaxNodes(2))
+		_, err := p.Parse([]byte(`'a''b'`))
+		assert.True(t, errors.Is(err, parser.ErrMaxNodes))
+	})
+}
+
+func TestMaxInputSize(t *testing.T) {
+	p := parser.New(processorQuote())
+	p.SetOptions(parser.MaxInputSize(5))
+	t.Run(`within`, func(t *testing.T) {
+		_, err := p.Parse([]byte(`'abc'`))
+		assert.NoError(t, err)
+	})
+	t.Run(`exceeded`, func(t *testing.T) {
+		_, err := p.Parse([]byte(`'abcd'`))
+		assert.True(t, errors.Is(err, parser.ErrMaxInputSize))
+		assert.EqualError(t, err, `1:6: maximum input size exceeded`)
+	})
+	t.Run(`stream`, func(t *testing.T) {
+		_, err := p.ParseReader(strings.NewReader(`'abcd'`))
+		assert.True(t, errors.Is(err, parser.ErrMaxInputSize))
+		_, err = p.ParseReader(strings.NewReader(`'abc'`))
+		assert.NoError(t, err)
+	})
+}
+
+func TestCopyText(t *testing.T) {
+	input := []byte(`text 'quote'`)
+	t.Run(`shared`, func(t *testing.T) {
+		node, err := parser.New(processorQuote()).Parse(input)
+		if !assert.NoError(t, err) {
+			return
+		}
+		text := node.(*ast.Document).Children[0].(*ast.Text)
+		assert.Equal(t, []byte(`text `), text.Content)
+		assert.True(t, &input[0] == &text.Content[0])
+		assert.Equal(t, len(text.Content), cap(text.Content))
+	})
+	t.Run(`copy`, func(t *testing.T) {
+		p := parser.New(processorQuote())
+		p.SetOptions(parser.CopyText())
+		node, err := p.Parse(input)
+		if !assert.NoError(t, err) {
+			return
+		}
+		text := node.(*ast.Document).Children[0].(*ast.Text)
+		assert.Equal(t, []byte(`text `), text.Content)
+		assert.False(t, &input[0] == &text.Content[0])
+	})
+	t.Run(`literal`, func(t *testing.T) {
+		p := parser.New(processorQuote(parser.Verbatim()))
+		p.SetOptions(parser.CopyText())
+		node, err := p.Parse(input)
+		if !assert.NoError(t, err) {
+			return
+		}
+		text := node.(*ast.Document).Children[1].(*quote).Children[0].(*ast.Text)
+		assert.Equal(t, []byte(`quote`), text.Content)
+		assert.False(t, &input[6] == &text.Content[0])
+	})
+}
@@ -25,68 +25,545 @@
 
 package parser
 
-import "github.com/biodebox/yaastr/ast"
+import (
+	"context"
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"io"
+)
 
 //go:generate mockery -name "Parser"
 
 type (
 	Parser interface {
 		AddProcessor(processors ...Processor)
+		AddProcessorFor(leading string, processors ...Processor)
+		AddMode(name string, processors ...Processor)
+		AddModeFor(name, leading string, processors ...Processor)
+		SetOptions(options ...Option)
 		Parse([]byte) (ast.Node, error)
+		ParseContext(context.Context, []byte) (ast.Node, error)
+		ParseReader(io.Reader) (ast.Node, error)
+		Reparse(old ast.Node, data []byte, edit Edit) (ast.Node, error)
 	}
 	Processor func(node ast.ParentNode, data []byte, parser func(ast.ParentNode, []byte) error) (int, error)
 	parser    struct {
-		processors []Processor
+		processors processorSet
+		modes      map[string]*processorSet
+		maxDepth   int
+		maxNodes   int
+		maxInput   int
+		copyText   bool
+		memo       bool
+		memoStats  *MemoStats
+		recovery   bool
+		syncPoints [][]byte
+		latest     *history
+	}
+	state struct {
+		*parser
+		ctx    context.Context
+		input  []byte
+		path   []ast.ParentNode
+		reader io.Reader
+		eof    bool
+		//base is offset of input in whole stream, origin is its position
+		base   int
+		origin ast.Position
+		nodes  int
+		//nested is parse bound once, so processor calls do not allocate it
+		nested func(ast.ParentNode, []byte) error
+		memo   map[memoKey]*memoEntry
+		hits   int64
+		misses int64
+		errors ErrorList
+		mode   *modeFrame
+		//expected is the farthest failure recorded by Expect
+		expected *ExpectedError
+		//origins are rules which created top level nodes in order of position, they are kept with Incremental option
+		origins []origin
+	}
+	builder struct {
+		*state
+		node  ast.ParentNode
+		index int
+		start int
+		//text is a sub-slice of input until it is owned
+		text  []byte
+		owned bool
+	}
+	literal struct {
+		ast.ParentNode
+	}
+	probe struct {
+		ast.ParentNode
+	}
+	locator struct {
+		ast.ParentNode
+		offset int
+	}
+	descent struct {
+		ast.ParentNode
+		match   func(ast.ParentNode, []byte, func(ast.ParentNode, []byte) error) (int, bool, error)
+		handled bool
+		n       int
+		ok      bool
+		err     error
+	}
+	doneCheck struct {
+		ast.ParentNode
 	}
+	//depthCheck asks parser callback whether depth is limited, so Descend allocates nothing without MaxDepth option
+	depthCheck struct {
+		ast.ParentNode
+	}
+)
+
+const chunkSize = 32 << 10
+
+var (
+	checkDone    = &doneCheck{}
+	depthLimited = &depthCheck{}
+	errDepth     = errors.New("depth")
+)
+
+var (
+	//ErrIncomplete is returned by processors which need input after the end of data, see More
+	ErrIncomplete = errors.New("incomplete input")
+	errMore       = errors.New("more input")
 )
 
 func New(processors ...Processor) Parser {
-	return &parser{processors: processors}
+	p := &parser{}
+	p.processors.add(nil, processors...)
+	return p
+}
+
+//Literal wraps node for the nested parser callback, content is appended as one text node without running processors
+func Literal(node ast.ParentNode) ast.ParentNode {
+	return &literal{ParentNode: node}
+}
+
+//More asks parser callback whether input may continue after data, it is true only for ParseReader before the end of stream.
+//Processor which result depends on the following input should return ErrIncomplete then, ParseReader retries it with more data.
+func More(parser func(ast.ParentNode, []byte) error, data []byte) bool {
+	return parser(&probe{}, data) == errMore
+}
+
+//Offset asks parser callback for position of data in whole input, data must be a sub-slice of processor data
+func Offset(parser func(ast.ParentNode, []byte) error, data []byte) int {
+	p := &locator{}
+	if err := parser(p, data); err != nil {
+		return 0
+	}
+	return p.offset
+}
+
+//Descend runs match for node which is built outside of the parse tree, like nodes of combinator rules.
+//Node counts as nesting level for MaxDepth and parsing stops when context of ParseContext is done.
+func Descend(
+	parser func(ast.ParentNode, []byte) error,
+	node ast.ParentNode,
+	data []byte,
+	match func(ast.ParentNode, []byte, func(ast.ParentNode, []byte) error) (int, bool, error),
+) (int, bool, error) {
+	if err := parser(depthLimited, data); err != errDepth {
+		if err != nil {
+			return 0, false, err
+		}
+		return match(node, data, parser)
+	}
+	d := &descent{ParentNode: node, match: match}
+	if err := parser(d, data); err != nil {
+		return 0, false, err
+	}
+	if !d.handled {
+		return match(node, data, parser)
+	}
+	return d.n, d.ok, d.err
+}
+
+//Done returns error when context of ParseContext is done, recursive rules call it to stop long backtracking
+func Done(parser func(ast.ParentNode, []byte) error, data []byte) error {
+	return parser(checkDone, data)
 }
 
 func (p *parser) AddProcessor(processors ...Processor) {
-	p.processors = append(p.processors, processors...)
+	p.processors.add(nil, processors...)
 }
 
 func (p *parser) Parse(data []byte) (ast.Node, error) {
+	return p.ParseContext(context.Background(), data)
+}
+
+//ParseContext stops parsing with ctx.Err() wrapped into *Error when ctx is done
+func (p *parser) ParseContext(ctx context.Context, data []byte) (ast.Node, error) {
 	doc := &ast.Document{}
-	return doc, p.parse(doc, data)
+	doc.SetSpan(ast.Span{End: len(data)})
+	s := p.newState(ctx, data, nil)
+	defer s.finish()
+	if p.maxInput > 0 && len(data) > p.maxInput {
+		return doc, s.error(p.maxInput, nil, ErrMaxInputSize)
+	}
+	err := s.parse(doc, data)
+	p.latest.set(doc, s.origins)
+	return doc, s.result(err)
+}
+
+//ParseReader reads input by chunks and builds the same tree as Parse, consumed input is released
+func (p *parser) ParseReader(reader io.Reader) (ast.Node, error) {
+	doc := &ast.Document{}
+	s := p.newState(context.Background(), nil, reader)
+	defer s.finish()
+	err := s.stream(doc)
+	doc.SetSpan(ast.Span{End: s.base + len(s.input)})
+	p.latest.set(doc, s.origins)
+	return doc, s.result(err)
+}
+
+func (p *parser) newState(ctx context.Context, input []byte, reader io.Reader) *state {
+	s := &state{parser: p, ctx: ctx, input: input, reader: reader, origin: ast.Position{Line: 1, Column: 1}}
+	s.nested = s.parse
+	if p.memo {
+		s.memo = make(map[memoKey]*memoEntry)
+	}
+	return s
+}
+
+func (s *state) parse(node ast.ParentNode, data []byte) error {
+	switch n := node.(type) {
+	case *literal:
+		if len(data) == 0 {
+			return nil
+		}
+		n.AppendNode(s.text(s.offset(data), data))
+		return s.count(1, s.offset(data))
+	case *probe:
+		if s.more(data) {
+			return errMore
+		}
+		return nil
+	case *locator:
+		n.offset = s.offset(data)
+		return nil
+	case *doneCheck:
+		return s.done(data)
+	case *depthCheck:
+		if err := s.done(data); err != nil {
+			return err
+		}
+		if s.maxDepth > 0 {
+			return errDepth
+		}
+		return nil
+	case *descent:
+		return s.descend(n, data)
+	case *memoCheck:
+		if s.memo != nil {
+			return errMemo
+		}
+		return nil
+	case *memoizer:
+		if s.memo != nil {
+			n.handled = true
+			n.n, n.ok, n.err = s.memoize(n.key, n.ParentNode, data, n.match)
+		}
+		return nil
+	case *expectation:
+		s.expect(s.offset(data), n.expected)
+		return nil
+	case *expectations:
+		n.err = s.expectedFrom(s.offset(data))
+		return nil
+	case *tokenStream:
+		return s.tokenize(n, data)
+	case *modeSwitch:
+		return s.switchMode(n, data)
+	case *protected:
+		return s.parseNode(n.ParentNode, data, n.ranges)
+	}
+	return s.parseNode(node, data, nil)
 }
 
-func (p *parser) parse(node ast.ParentNode, data []byte) error {
-	var text []byte
-	index := len(node.GetChildren())
-LoopMain:
+//parseNode runs processors for data of node, ranges of data are added to text as they are
+func (s *state) parseNode(node ast.ParentNode, data []byte, ranges [][2]int) error {
+	s.path = append(s.path, node)
+	defer func() {
+		s.path = s.path[:len(s.path)-1]
+	}()
+	if s.maxDepth > 0 && len(s.path) > s.maxDepth {
+		return s.error(s.offset(data), nil, ErrMaxDepth)
+	}
+	b := s.builder(node, data)
+	start := s.offset(data)
 	for len(data) > 0 {
-		for _, processor := range p.processors {
-			if offset, err := processor(node, data, p.parse); err != nil {
-				return err
-			} else if offset != 0 {
-				if offset > len(data) {
-					data = nil
-				} else {
-					data = data[offset:]
-				}
-				if len(text) > 0 {
-					node.InsertNode(index, ast.NewText(text...))
-					text = []byte{}
+		if err := s.done(data); err != nil {
+			return err
+		}
+		if len(ranges) > 0 {
+			//range is added to text only when it starts here, processors which consumed its start own it
+			if position := s.offset(data) - start; position >= ranges[0][0] {
+				for n := ranges[0][1] - position; n > 0 && position == ranges[0][0]; n-- {
+					b.append(data)
+					data = data[1:]
 				}
-				index = len(node.GetChildren())
-				continue LoopMain
+				ranges = ranges[1:]
+				continue
+			}
+		}
+		offset, err := b.step(data)
+		if err != nil {
+			return err
+		}
+		data = data[offset:]
+	}
+	return b.flush()
+}
+
+//descend runs match of descent with its node on the path
+func (s *state) descend(d *descent, data []byte) error {
+	s.path = append(s.path, d.ParentNode)
+	defer func() {
+		s.path = s.path[:len(s.path)-1]
+	}()
+	if s.maxDepth > 0 && len(s.path) > s.maxDepth {
+		return s.error(s.offset(data), nil, ErrMaxDepth)
+	}
+	d.handled = true
+	d.n, d.ok, d.err = d.match(d.ParentNode, data, s.nested)
+	return nil
+}
+
+//stream parses top level of document reading input when data ends or processor needs more of it
+func (s *state) stream(doc *ast.Document) error {
+	s.path = append(s.path, doc)
+	var data []byte
+	b := s.builder(doc, data)
+	for len(data) > 0 || !s.eof {
+		if len(data) == 0 {
+			if err := s.read(&data); err != nil {
+				return err
 			}
+			continue
 		}
-		if len(data) > 0 {
-			text = append(text, data[0])
-			if len(data) > 1 {
-				data = data[1:]
-			} else {
-				data = nil
+		if err := s.done(data); err != nil {
+			return err
+		}
+		nodes, origins := s.nodes, len(s.origins)
+		offset, err := s.process(doc, data)
+		if err != nil {
+			offset, err = b.recover(data, err)
+		}
+		if !s.eof && (errors.Is(err, ErrIncomplete) || offset == len(data)) {
+			b.rollback()
+			s.nodes, s.origins = nodes, s.origins[:origins]
+			if err := s.read(&data); err != nil {
+				return err
 			}
+			continue
+		}
+		if err != nil {
+			return err
 		}
+		if offset == 0 {
+			b.append(data)
+			offset = 1
+		} else if err := b.consume(data, offset); err != nil {
+			return err
+		}
+		data = data[offset:]
+	}
+	return b.flush()
+}
+
+//read releases consumed input and appends next chunk to data, chunk grows with unconsumed data so retries are rare
+func (s *state) read(data *[]byte) error {
+	offset := s.offset(*data)
+	s.origin = s.position(offset)
+	s.base = offset
+	input := make([]byte, len(*data), 2*len(*data)+chunkSize)
+	copy(input, *data)
+	n, err := io.ReadFull(s.reader, input[len(input):cap(input)])
+	s.input = input[:len(input)+n]
+	*data = s.input
+	if s.memo != nil {
+		s.memo = make(map[memoKey]*memoEntry)
+	}
+	if s.maxInput > 0 && s.base+len(s.input) > s.maxInput {
+		return s.error(s.maxInput, nil, ErrMaxInputSize)
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		s.eof = true
+		return nil
+	}
+	return err
+}
+
+//process runs processors until one of them consumes data
+func (s *state) process(node ast.ParentNode, data []byte) (int, error) {
+	return s.apply(s.current().candidates(data[0]), node, data)
+}
+
+//apply runs rules in order until one of them consumes data, the rule is recorded as origin of top level nodes
+func (s *state) apply(rules []*rule, node ast.ParentNode, data []byte) (int, error) {
+	for _, r := range rules {
+		if offset, err := s.run(r, node, data); err != nil {
+			return 0, s.error(s.offset(data), r.processor, err)
+		} else if offset != 0 {
+			if offset > len(data) {
+				offset = len(data)
+			}
+			if s.latest != nil && len(s.path) == 1 {
+				s.origins = append(s.origins, origin{start: s.offset(data), rule: r})
+			}
+			return offset, nil
+		}
+	}
+	return 0, nil
+}
+
+//more reports whether input may continue after data
+func (s *state) more(data []byte) bool {
+	return s.reader != nil && !s.eof && s.offset(data)+len(data) == s.base+len(s.input)
+}
+
+//done returns error of finished context
+func (s *state) done(data []byte) error {
+	select {
+	case <-s.ctx.Done():
+		return s.error(s.offset(data), nil, s.ctx.Err())
+	default:
+		return nil
+	}
+}
+
+//count adds created nodes to total number of nodes
+func (s *state) count(nodes, offset int) error {
+	s.nodes += nodes
+	if s.maxNodes > 0 && s.nodes > s.maxNodes {
+		return s.error(offset, nil, ErrMaxNodes)
+	}
+	return nil
+}
+
+func (s *state) builder(node ast.ParentNode, data []byte) *builder {
+	return &builder{state: s, node: node, index: len(node.GetChildren()), start: s.offset(data)}
+}
+
+//text creates text node sharing content unless CopyText option is set
+func (s *state) text(start int, content []byte) *ast.Text {
+	content = content[:len(content):len(content)]
+	if s.copyText {
+		content = append([]byte(nil), content...)
+	}
+	text := ast.NewText(content...)
+	text.SetSpan(ast.Span{Start: start, End: start + len(content)})
+	return text
+}
+
+//error wraps err into *Error unless it already came from a nested parse
+func (s *state) error(offset int, processor Processor, err error) error {
+	var parseError *Error
+	if errors.As(err, &parseError) {
+		return err
+	}
+	var expected []string
+	var expectedError *ExpectedError
+	if errors.As(err, &expectedError) {
+		offset, expected = expectedError.Offset, expectedError.Expected
+	}
+	return &Error{
+		Position:  s.position(offset),
+		Path:      append([]ast.ParentNode(nil), s.path...),
+		Processor: processor,
+		Expected:  expected,
+		Err:       err,
+	}
+}
+
+//offset returns position of data in parsed input, data must be a sub-slice of the input
+func (s *state) offset(data []byte) int {
+	return s.base + cap(s.input) - cap(data)
+}
+
+func (s *state) position(offset int) ast.Position {
+	position := ast.PositionOf(s.input, offset-s.base)
+	if position.Line == 1 {
+		position.Column += s.origin.Column - 1
+	}
+	position.Line += s.origin.Line - 1
+	position.Offset = offset
+	return position
+}
+
+//step runs processors at the beginning of data and adds created nodes or the first byte as text, it returns consumed length
+func (b *builder) step(data []byte) (int, error) {
+	offset, err := b.process(b.node, data)
+	if err != nil {
+		offset, err = b.recover(data, err)
 	}
-	if len(text) > 0 {
-		node.InsertNode(index, ast.NewText(text...))
+	if err != nil {
+		return 0, err
 	}
+	if offset == 0 {
+		b.append(data)
+		return 1, nil
+	}
+	return offset, b.consume(data, offset)
+}
+
+//append adds first byte of data to text
+func (b *builder) append(data []byte) {
+	length := len(b.text)
+	switch {
+	case length == 0:
+		b.start = b.offset(data)
+		b.text = data[:1]
+	case length < cap(b.text) && &b.text[:length+1][length] == &data[0]:
+		b.text = b.text[:length+1]
+	case b.owned:
+		b.text = append(b.text, data[0])
+	default:
+		b.text = append(b.text[:length:length], data[0])
+		b.owned = true
+	}
+}
+
+//consume sets span of nodes added by processor and inserts text preceding them
+func (b *builder) consume(data []byte, offset int) error {
+	position := b.offset(data)
+	nodes := b.node.GetChildren()[b.index:]
+	setSpan(ast.Span{Start: position, End: position + offset}, nodes...)
+	if err := b.count(len(nodes), position); err != nil {
+		return err
+	}
+	if err := b.flush(); err != nil {
+		return err
+	}
+	b.index = len(b.node.GetChildren())
 	return nil
+}
 
+//rollback deletes nodes added by processor
+func (b *builder) rollback() {
+	for length := len(b.node.GetChildren()); length > b.index; length-- {
+		b.node.DeleteNode(length - 1)
+	}
+}
+
+func (b *builder) flush() error {
+	if len(b.text) == 0 {
+		return nil
+	}
+	b.node.InsertNode(b.index, b.state.text(b.start, b.text))
+	b.text, b.owned = nil, false
+	return b.count(1, b.start)
+}
+
+func setSpan(span ast.Span, nodes ...ast.Node) {
+	for _, node := range nodes {
+		if spanned, ok := node.(ast.Spanned); ok && spanned.GetSpan() == (ast.Span{}) {
+			spanned.SetSpan(span)
+		}
+	}
 }
@@ -26,10 +26,15 @@
 package parser_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"github.com/biodebox/yaastr/ast"
 	"github.com/biodebox/yaastr/parser"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 type (
@@ -39,6 +44,14 @@ type (
 	}
 )
 
+func (q *quote) CloneNode() ast.Node {
+	return &quote{Container: ast.NewContainer(), double: q.double}
+}
+
+func (q *quote) EqualNode(other ast.Node) bool {
+	return q.double == other.(*quote).double
+}
+
 func TestParser_Parse(t *testing.T) {
 	t.Run(`empty`, func(t *testing.T) {
 		node, err := parser.New().Parse([]byte{})
@@ -53,8 +66,8 @@ func TestParser_Parse(t *testing.T) {
 		if !assert.NoError(t, err) {
 			return
 		}
-		doc := &ast.Document{}
-		doc.AppendNode(ast.NewText([]byte(`quote`)...))
+		doc := document(5)
+		doc.AppendNode(text(`quote`, 0))
 		assert.Equal(t, doc, node)
 	})
 	t.Run(`processor with text`, func(t *testing.T) {
@@ -62,11 +75,11 @@ func TestParser_Parse(t *testing.T) {
 		if !assert.NoError(t, err) {
 			return
 		}
-		doc := &ast.Document{}
-		doc.AppendNode(&quote{
-			Container: ast.NewContainer(ast.NewText([]byte(`quote`)...)),
-		})
-		doc.AppendNode(ast.NewText([]byte(` text`)...))
+		doc := document(12)
+		doc.AppendNode(span(&quote{
+			Container: ast.NewContainer(text(`quote`, 1)),
+		}, 0, 7))
+		doc.AppendNode(text(` text`, 7))
 		assert.Equal(t, doc, node)
 	})
 	t.Run(`quote with quote with text`, func(t *testing.T) {
@@ -74,18 +87,18 @@ func TestParser_Parse(t *testing.T) {
 		if !assert.NoError(t, err) {
 			return
 		}
-		doc := &ast.Document{}
-		doc.AppendNode(&quote{
+		doc := document(40)
+		doc.AppendNode(span(&quote{
 			Container: ast.NewContainer(
-				ast.NewText([]byte(`quote after `)...),
-				&quote{
-					Container: ast.NewContainer(ast.NewText([]byte(`double quote`)...)),
+				text(`quote after `, 1),
+				span(&quote{
+					Container: ast.NewContainer(text(`double quote`, 14)),
 					double:    true,
-				},
-				ast.NewText([]byte(` before`)...),
+				}, 13, 27),
+				text(` before`, 27),
 			),
-		})
-		doc.AppendNode(ast.NewText([]byte(` text`)...))
+		}, 0, 35))
+		doc.AppendNode(text(` text`, 35))
 		assert.Equal(t, doc, actualDocument)
 	})
 	t.Run(`out of range`, func(t *testing.T) {
@@ -93,13 +106,191 @@ func TestParser_Parse(t *testing.T) {
 		if !assert.NoError(t, err) {
 			return
 		}
-		doc := &ast.Document{}
-		doc.AppendNode(ast.NewText([]byte(`te`)...))
+		doc := document(4)
+		doc.AppendNode(text(`te`, 0))
 		assert.Equal(t, doc, actualDocument)
 	})
+	t.Run(`span`, func(t *testing.T) {
+		data := []byte("first\n'second' third")
+		actualDocument, err := parser.New(processorQuote()).Parse(data)
+		if !assert.NoError(t, err) {
+			return
+		}
+		children := actualDocument.(*ast.Document).GetChildren()
+		if !assert.Len(t, children, 3) {
+			return
+		}
+		nodeSpan, ok := ast.SpanOf(children[1])
+		if !assert.True(t, ok) {
+			return
+		}
+		assert.Equal(t, []byte(`'second'`), nodeSpan.Bytes(data))
+		assert.Equal(t, ast.Position{Offset: 6, Line: 2, Column: 1}, ast.PositionOf(data, nodeSpan.Start))
+		assert.Equal(t, ast.Position{Offset: 14, Line: 2, Column: 9}, ast.PositionOf(data, nodeSpan.End))
+	})
+	t.Run(`clone`, func(t *testing.T) {
+		actualDocument, err := parser.New(processorQuote(), processorDoubleQuote()).Parse([]byte(`'a "b"' c`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		clone := ast.Clone(actualDocument)
+		assert.Equal(t, actualDocument, clone)
+		original := actualDocument.(*ast.Document).Children[0].(*quote).Children[0].(*ast.Text)
+		clone.(*ast.Document).Children[0].(*quote).Children[0].(*ast.Text).Content[0] = 'x'
+		assert.Equal(t, []byte(`a `), original.Content)
+	})
+	t.Run(`equal`, func(t *testing.T) {
+		p := parser.New(processorQuote(), processorDoubleQuote())
+		single, err := p.Parse([]byte(`x 'a'`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		double, err := p.Parse([]byte(`x "a"`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.False(t, ast.Equal(single, double))
+		expected := &ast.Document{}
+		expected.AppendNode(ast.NewText([]byte(`x `)...), &quote{Container: ast.NewContainer(ast.NewText([]byte(`a`)...))})
+		assert.True(t, ast.Equal(expected, single))
+		changes := ast.Diff(single, double)
+		if !assert.Len(t, changes, 2) {
+			return
+		}
+		assert.Equal(t, ast.Deleted, changes[0].Kind)
+		assert.Equal(t, ast.Inserted, changes[1].Kind)
+	})
+}
+
+func TestParser_ParseReader(t *testing.T) {
+	factory := func() ast.ParentNode {
+		return &quote{Container: ast.NewContainer()}
+	}
+	for name, test := range map[string]struct {
+		input      string
+		processors []parser.Processor
+	}{
+		`empty`: {},
+		`text`:  {input: "first\nsecond"},
+		`quotes`: {
+			input:      `a 'b "c" d' e`,
+			processors: []parser.Processor{processorQuote(), processorDoubleQuote()},
+		},
+		`unterminated text`: {
+			input:      `a 'b "c" d`,
+			processors: []parser.Processor{processorQuote(parser.OnUnterminated(parser.UnterminatedText)), processorDoubleQuote()},
+		},
+		`unterminated close`: {
+			input:      `a 'b "c" d`,
+			processors: []parser.Processor{processorQuote(parser.OnUnterminated(parser.UnterminatedClose)), processorDoubleQuote()},
+		},
+		`delimiters`: {
+			input:      `«x «y» z» {{ w }} { {`,
+			processors: []parser.Processor{parser.ProcessorBalanced('«', '»', factory), parser.ProcessorByStrings(`{{`, `}}`, factory)},
+		},
+		`doubling`: {
+			input:      `'it''s' 'a'`,
+			processors: []parser.Processor{processorQuote(parser.EscapeByDoubling(), parser.Unescape())},
+		},
+		`large`: {
+			input:      strings.Repeat(`text 'quote' `, 10000) + `'` + strings.Repeat(`long `, 10000) + `'`,
+			processors: []parser.Processor{processorQuote()},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			expected, err := parser.New(test.processors...).Parse([]byte(test.input))
+			if !assert.NoError(t, err) {
+				return
+			}
+			actual, err := parser.New(test.processors...).ParseReader(strings.NewReader(test.input))
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, expected, actual)
+			actual, err = parser.New(test.processors...).ParseReader(iotest.OneByteReader(strings.NewReader(test.input)))
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, expected, actual)
+		})
+	}
+	t.Run(`error position`, func(t *testing.T) {
+		input := strings.Repeat("line\n", 10000) + `text 'quote`
+		_, expected := parser.New(processorQuote()).Parse([]byte(input))
+		_, actual := parser.New(processorQuote()).ParseReader(iotest.HalfReader(strings.NewReader(input)))
+		assert.EqualError(t, actual, `10001:6: unterminated "'"`)
+		assert.EqualError(t, actual, expected.Error())
+	})
+	t.Run(`read error`, func(t *testing.T) {
+		_, err := parser.New(processorQuote()).ParseReader(iotest.TimeoutReader(bytes.NewReader([]byte(`'text'`))))
+		assert.True(t, errors.Is(err, iotest.ErrTimeout))
+	})
 }
 
-func processorQuote() parser.Processor {
+func TestParser_ParseContext(t *testing.T) {
+	t.Run(`background`, func(t *testing.T) {
+		node, err := parser.New(processorQuote()).ParseContext(context.Background(), []byte(`'quote'`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		expected, _ := parser.New(processorQuote()).Parse([]byte(`'quote'`))
+		assert.Equal(t, expected, node)
+	})
+	t.Run(`canceled`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := parser.New(processorQuote()).ParseContext(ctx, []byte(`'quote'`))
+		assert.True(t, errors.Is(err, context.Canceled))
+		var parseError *parser.Error
+		if !assert.True(t, errors.As(err, &parseError)) {
+			return
+		}
+		assert.Equal(t, 0, parseError.Offset)
+		assert.Nil(t, parseError.Processor)
+	})
+	t.Run(`nested`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		canceling := func(node ast.ParentNode, data []byte, parser func(ast.ParentNode, []byte) error) (int, error) {
+			if data[0] == '!' {
+				cancel()
+			}
+			return 0, nil
+		}
+		_, err := parser.New(processorQuote(), canceling).ParseContext(ctx, []byte("text\n'quo!te'"))
+		assert.EqualError(t, err, `2:6: context canceled`)
+		var parseError *parser.Error
+		if !assert.True(t, errors.As(err, &parseError)) {
+			return
+		}
+		assert.Equal(t, 10, parseError.Offset)
+		assert.Len(t, parseError.Path, 2)
+	})
+	t.Run(`deadline`, func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 0)
+		defer cancel()
+		<-ctx.Done()
+		_, err := parser.New().ParseContext(ctx, []byte(`text`))
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	})
+}
+
+func document(length int) *ast.Document {
+	doc := &ast.Document{}
+	doc.SetSpan(ast.Span{End: length})
+	return doc
+}
+
+func text(content string, start int) *ast.Text {
+	return span(ast.NewText([]byte(content)...), start, start+len(content)).(*ast.Text)
+}
+
+func span(node ast.Node, start, end int) ast.Node {
+	node.(ast.Spanned).SetSpan(ast.Span{Start: start, End: end})
+	return node
+}
+
+func processorQuote(options ...parser.DelimiterOption) parser.Processor {
 	return parser.ProcessorByRune(
 		'\'',
 		'\'',
@@ -108,10 +299,11 @@ func processorQuote() parser.Processor {
 				Container: ast.NewContainer(),
 			}
 		},
+		options...,
 	)
 }
 
-func processorDoubleQuote() parser.Processor {
+func processorDoubleQuote(options ...parser.DelimiterOption) parser.Processor {
 	return parser.ProcessorByRune(
 		'"',
 		'"',
@@ -121,6 +313,7 @@ func processorDoubleQuote() parser.Processor {
 				double:    true,
 			}
 		},
+		options...,
 	)
 }
 
@@ -132,3 +325,28 @@ func processorOutRange() parser.Processor {
 		return 10, nil
 	}
 }
+
+func TestOffset(t *testing.T) {
+	var offsets []int
+	p := parser.New(func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, error) {
+		if data[0] != '!' {
+			return 0, nil
+		}
+		offsets = append(offsets, parser.Offset(parse, data), parser.Offset(parse, data[1:]))
+		return 1, nil
+	})
+	t.Run(`parse`, func(t *testing.T) {
+		offsets = nil
+		_, err := p.Parse([]byte(`ab!c!`))
+		if assert.NoError(t, err) {
+			assert.Equal(t, []int{2, 3, 4, 5}, offsets)
+		}
+	})
+	t.Run(`reader`, func(t *testing.T) {
+		offsets = nil
+		_, err := p.ParseReader(iotest.OneByteReader(strings.NewReader(`ab!c!`)))
+		if assert.NoError(t, err) {
+			assert.Equal(t, []int{2, 3, 4, 5}, offsets)
+		}
+	})
+}
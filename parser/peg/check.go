@@ -0,0 +1,189 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package peg
+
+import (
+	"github.com/biodebox/yaastr/parser"
+)
+
+//check reports redefined and undefined rules, reversed class ranges and left recursion
+func (g *Grammar) check(source []byte) error {
+	var errs parser.ErrorList
+	g.names = make(map[string]*definition, len(g.definitions))
+	for _, d := range g.definitions {
+		if _, ok := g.names[d.name]; ok {
+			errs.Add(newError(source, d.span.Start, &RedefinedError{Name: d.name}))
+			continue
+		}
+		g.names[d.name] = d
+	}
+	for _, d := range g.definitions {
+		walk(d.expression, func(e expression) {
+			switch e := e.(type) {
+			case reference:
+				if g.names[e.name] == nil {
+					errs.Add(newError(source, e.span.Start, &UndefinedError{Name: e.name}))
+				}
+			case class:
+				for _, r := range e.ranges {
+					if r[0] > r[1] {
+						errs.Add(newError(source, e.span.Start, &RangeError{Low: r[0], High: r[1]}))
+					}
+				}
+			}
+		})
+	}
+	if len(errs) == 0 {
+		g.checkLeftRecursion(source, &errs)
+	}
+	errs.Sort()
+	return errs.Err()
+}
+
+//checkLeftRecursion finds cycles of rules referenced before any input is consumed
+func (g *Grammar) checkLeftRecursion(source []byte, errs *parser.ErrorList) {
+	nullable := g.nullable()
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(g.definitions))
+	var stack []string
+	var visit func(name string)
+	visit = func(name string) {
+		switch state[name] {
+		case visiting:
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] == name {
+					rules := append(append([]string(nil), stack[i:]...), name)
+					errs.Add(newError(source, g.names[name].span.Start, &LeftRecursionError{Rules: rules}))
+					return
+				}
+			}
+			return
+		case visited:
+			return
+		}
+		state[name] = visiting
+		stack = append(stack, name)
+		leftmost(g.names[name].expression, nullable, visit)
+		stack = stack[:len(stack)-1]
+		state[name] = visited
+	}
+	for _, d := range g.definitions {
+		visit(d.name)
+	}
+}
+
+//nullable returns rules matching empty input
+func (g *Grammar) nullable() map[string]bool {
+	nullable := make(map[string]bool, len(g.definitions))
+	for changed := true; changed; {
+		changed = false
+		for _, d := range g.definitions {
+			if !nullable[d.name] && isNullable(d.expression, nullable) {
+				nullable[d.name] = true
+				changed = true
+			}
+		}
+	}
+	return nullable
+}
+
+func isNullable(e expression, nullable map[string]bool) bool {
+	switch e := e.(type) {
+	case choice:
+		for _, alternative := range e {
+			if isNullable(alternative, nullable) {
+				return true
+			}
+		}
+		return false
+	case sequence:
+		for _, item := range e {
+			if !isNullable(item, nullable) {
+				return false
+			}
+		}
+		return true
+	case repetition:
+		return e.min == 0 || isNullable(e.expression, nullable)
+	case optional, predicate:
+		return true
+	case reference:
+		return nullable[e.name]
+	case literal:
+		return len(e) == 0
+	}
+	return false
+}
+
+//leftmost visits rules which may be referenced at the start of expression
+func leftmost(e expression, nullable map[string]bool, visit func(name string)) {
+	switch e := e.(type) {
+	case choice:
+		for _, alternative := range e {
+			leftmost(alternative, nullable, visit)
+		}
+	case sequence:
+		for _, item := range e {
+			leftmost(item, nullable, visit)
+			if !isNullable(item, nullable) {
+				return
+			}
+		}
+	case repetition:
+		leftmost(e.expression, nullable, visit)
+	case optional:
+		leftmost(e.expression, nullable, visit)
+	case predicate:
+		leftmost(e.expression, nullable, visit)
+	case reference:
+		visit(e.name)
+	}
+}
+
+//walk calls f for expression and its subexpressions
+func walk(e expression, f func(expression)) {
+	f(e)
+	switch e := e.(type) {
+	case choice:
+		for _, alternative := range e {
+			walk(alternative, f)
+		}
+	case sequence:
+		for _, item := range e {
+			walk(item, f)
+		}
+	case repetition:
+		walk(e.expression, f)
+	case optional:
+		walk(e.expression, f)
+	case predicate:
+		walk(e.expression, f)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package peg
+
+import (
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/biodebox/yaastr/parser/combinator"
+	"unicode"
+	"unicode/utf8"
+)
+
+//compile converts definitions to rules and returns rule of start definition
+func (g *Grammar) compile() combinator.Rule {
+	rules := make(map[string]*combinator.Rule, len(g.definitions))
+	for _, d := range g.definitions {
+		rules[d.name] = new(combinator.Rule)
+	}
+	for _, d := range g.definitions {
+		rule := compileExpression(d.expression, rules)
+		if exported(d.name) {
+			rule = guard(d.name, g.first(d.name), combinator.Memo(combinator.Map(rule, nodeFactory(d.name))))
+		} else {
+			rule = combinator.Memo(rule)
+		}
+		*rules[d.name] = rule
+	}
+	return *rules[g.definitions[0].name]
+}
+
+func compileExpression(e expression, rules map[string]*combinator.Rule) combinator.Rule {
+	switch e := e.(type) {
+	case choice:
+		return combinator.Choice(compileExpressions(e, rules)...)
+	case sequence:
+		return combinator.Seq(compileExpressions(e, rules)...)
+	case repetition:
+		if e.min == 0 {
+			return combinator.Many(compileExpression(e.expression, rules))
+		}
+		return combinator.Many1(compileExpression(e.expression, rules))
+	case optional:
+		return combinator.Optional(compileExpression(e.expression, rules))
+	case predicate:
+		if e.not {
+			return combinator.Not(compileExpression(e.expression, rules))
+		}
+		return combinator.Lookahead(compileExpression(e.expression, rules))
+	case reference:
+		return combinator.Ref(rules[e.name])
+	case literal:
+		if len(e) == 0 {
+			return combinator.Seq()
+		}
+		return combinator.Literal(string(e))
+	case class:
+		return combinator.CharClass(e.String())
+	}
+	return combinator.Any()
+}
+
+func compileExpressions(expressions []expression, rules map[string]*combinator.Rule) []combinator.Rule {
+	compiled := make([]combinator.Rule, 0, len(expressions))
+	for _, e := range expressions {
+		compiled = append(compiled, compileExpression(e, rules))
+	}
+	return compiled
+}
+
+//guard skips rule for data which can not start its match, so nodes are not created in vain
+func guard(name string, set firstSet, rule combinator.Rule) combinator.Rule {
+	if set == nil {
+		return rule
+	}
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+		if len(data) > 0 && !set[data[0]] {
+			parser.Expect(parse, data, name)
+			return 0, false, nil
+		}
+		return rule(node, data, parse)
+	}
+}
+
+func nodeFactory(name string) func() ast.ParentNode {
+	return func() ast.ParentNode {
+		return NewNode(name)
+	}
+}
+
+//exported reports whether rule creates nodes
+func exported(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return unicode.IsUpper(r)
+}
@@ -0,0 +1,152 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package peg
+
+import (
+	"unicode/utf8"
+)
+
+type (
+	//firstSet marks bytes which may start input matched by expression, nil means any byte
+	firstSet *[256]bool
+)
+
+//first returns first bytes of input matched by definition, nil when definition matches empty input or any byte
+func (g *Grammar) first(name string) firstSet {
+	if g.firsts == nil {
+		g.firsts = make(map[string]firstSet, len(g.definitions))
+		g.nullables = g.nullable()
+	}
+	if g.nullables[name] {
+		return nil
+	}
+	return g.consumed(name)
+}
+
+//consumed returns first bytes of non-empty input matched by definition
+func (g *Grammar) consumed(name string) firstSet {
+	if set, ok := g.firsts[name]; ok {
+		return set
+	}
+	set := g.firstOf(g.names[name].expression)
+	g.firsts[name] = set
+	return set
+}
+
+//firstOf returns first bytes of non-empty input matched by expression, it relies on absence of left recursion
+func (g *Grammar) firstOf(e expression) firstSet {
+	set := &[256]bool{}
+	switch e := e.(type) {
+	case choice:
+		for _, alternative := range e {
+			if !union(set, g.firstOf(alternative)) {
+				return nil
+			}
+		}
+	case sequence:
+		for _, item := range e {
+			if !union(set, g.firstOf(item)) {
+				return nil
+			}
+			if !isNullable(item, g.nullables) {
+				break
+			}
+		}
+	case repetition:
+		return g.firstOf(e.expression)
+	case optional:
+		return g.firstOf(e.expression)
+	case predicate:
+	case reference:
+		return g.consumed(e.name)
+	case literal:
+		if len(e) == 0 {
+			break
+		}
+		set[e[0]] = true
+	case class:
+		if e.negate {
+			return nil
+		}
+		for _, r := range e.ranges {
+			if r[0] <= utf8.RuneError && r[1] >= utf8.RuneError {
+				return nil
+			}
+			addRange(set, r[0], r[1])
+		}
+	default:
+		return nil
+	}
+	return set
+}
+
+//union adds other to set and reports whether other is limited
+func union(set *[256]bool, other firstSet) bool {
+	if other == nil {
+		return false
+	}
+	for b, ok := range other {
+		set[b] = set[b] || ok
+	}
+	return true
+}
+
+//addRange marks first bytes of runes in range, range is split by length of encoding
+func addRange(set *[256]bool, low, high rune) {
+	for _, limit := range []rune{0x7f, 0x7ff, 0xffff, utf8.MaxRune} {
+		if low > high {
+			return
+		}
+		if low > limit {
+			continue
+		}
+		end := high
+		if end > limit {
+			end = limit
+		}
+		for b := firstByte(low); b <= firstByte(end); b++ {
+			set[b] = true
+		}
+		low = limit + 1
+	}
+}
+
+func firstByte(r rune) int {
+	buffer := [utf8.UTFMax]byte{}
+	utf8.EncodeRune(buffer[:], r)
+	return int(buffer[0])
+}
+
+//leading returns string of bytes of set for parser.AddProcessorFor, bytes are sorted so no pair of them forms a rune
+func leading(set firstSet) string {
+	result := make([]byte, 0, len(set))
+	for b, ok := range set {
+		if ok {
+			result = append(result, byte(b))
+		}
+	}
+	return string(result)
+}
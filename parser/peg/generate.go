@@ -0,0 +1,249 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package peg
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type (
+	generator struct {
+		buffer bytes.Buffer
+		count  int
+		runes  bool
+	}
+)
+
+const signature = "(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error)"
+
+//Generate writes Go source of package with recursive descent parser for grammar, the parser builds the same tree as New
+func (g *Grammar) Generate(w io.Writer, pkg string) error {
+	gen := &generator{}
+	for _, d := range g.definitions {
+		gen.definition(d, g.first(d.name))
+	}
+	header := &bytes.Buffer{}
+	fmt.Fprintf(header, "// Code generated by yaastr-gen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	fmt.Fprintf(header, "\t\"github.com/biodebox/yaastr/ast\"\n\t\"github.com/biodebox/yaastr/parser\"\n")
+	fmt.Fprintf(header, "\t\"github.com/biodebox/yaastr/parser/combinator\"\n\t\"github.com/biodebox/yaastr/parser/peg\"\n")
+	if gen.runes {
+		fmt.Fprintf(header, "\t\"unicode/utf8\"\n")
+	}
+	fmt.Fprintf(header, ")\n\n")
+	fmt.Fprintf(header, "// New returns parser of grammar\nfunc New() parser.Parser {\n")
+	if set := g.first(g.definitions[0].name); set != nil {
+		fmt.Fprintf(header, "\tp := parser.New()\n\tp.AddProcessorFor(%q, Processor())\n\treturn p\n}\n\n", leading(set))
+	} else {
+		fmt.Fprintf(header, "\treturn parser.New(Processor())\n}\n\n")
+	}
+	fmt.Fprintf(header, "// NewStrict returns parser of grammar which fails where start rule does not match\nfunc NewStrict() parser.Parser {\n")
+	fmt.Fprintf(header, "\treturn parser.New(combinator.Expect(%s).Processor())\n}\n\n", ruleName(g.definitions[0].name))
+	fmt.Fprintf(header, "// Processor returns processor of start rule %s\nfunc Processor() parser.Processor {\n", g.definitions[0].name)
+	fmt.Fprintf(header, "\treturn combinator.Rule(%s).Processor()\n}\n", ruleName(g.definitions[0].name))
+	fmt.Fprintf(header, "\n// memoKey identifies rules in memo table of parser\ntype memoKey string\n")
+	header.Write(gen.buffer.Bytes())
+	source, err := format.Source(header.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(source)
+	return err
+}
+
+func (g *generator) definition(d *definition, set firstSet) {
+	body := g.expression(d.expression)
+	if !exported(d.name) {
+		g.function(ruleName(d.name))
+		g.printf("if err := parser.Done(parse, data); err != nil {\nreturn 0, false, err\n}\n")
+		g.printf("return parser.Memoize(parse, memoKey(%q), node, data, %s)\n}\n", d.name, body)
+		return
+	}
+	if set != nil {
+		g.printf("\nvar first_%s = [256]bool{", d.name)
+		for b, ok := range set {
+			if ok {
+				g.printf("%#02x: true, ", b)
+			}
+		}
+		g.printf("}\n")
+	}
+	g.function(ruleName(d.name))
+	if set != nil {
+		g.printf("if len(data) > 0 && !first_%s[data[0]] {\nparser.Expect(parse, data, %q)\nreturn 0, false, nil\n}\n", d.name, d.name)
+	}
+	g.printf("return parser.Memoize(parse, memoKey(%q), node, data, %s)\n}\n", d.name, matchName(d.name))
+	g.function(matchName(d.name))
+	g.printf("child := peg.NewNode(%q)\n", d.name)
+	g.printf("n, ok, err := parser.Descend(parse, child, data, %s)\n", body)
+	g.printf("if err != nil || !ok {\nreturn 0, false, err\n}\n")
+	g.printf("if err := combinator.Attach(node, child, data[:n], parse); err != nil {\nreturn 0, false, err\n}\n")
+	g.printf("return n, true, nil\n}\n")
+}
+
+//expression writes function matching expression and returns its name, code mirrors combinator rules
+func (g *generator) expression(e expression) string {
+	if r, ok := e.(reference); ok {
+		return ruleName(r.name)
+	}
+	var names []string
+	switch e := e.(type) {
+	case choice:
+		names = g.expressions(e)
+	case sequence:
+		names = g.expressions(e)
+	case repetition:
+		names = []string{g.expression(e.expression)}
+	case optional:
+		names = []string{g.expression(e.expression)}
+	case predicate:
+		names = []string{g.expression(e.expression)}
+	}
+	g.count++
+	name := fmt.Sprintf("expression%d", g.count)
+	g.function(name)
+	switch e := e.(type) {
+	case choice:
+		g.printf("index := len(node.GetChildren())\n")
+		for _, f := range names {
+			g.printf("if n, ok, err := %s(node, data, parse); err != nil {\n", f)
+			g.printf("combinator.Truncate(node, index)\nreturn 0, false, err\n} else if ok {\nreturn n, true, nil\n}\n")
+			g.printf("combinator.Truncate(node, index)\n")
+		}
+		g.printf("return 0, false, nil\n")
+	case sequence:
+		if len(names) == 0 {
+			g.printf("return 0, true, nil\n")
+			break
+		}
+		g.printf("index := len(node.GetChildren())\noffset := 0\n")
+		for _, f := range names {
+			g.printf("if n, ok, err := %s(node, data[offset:], parse); err != nil || !ok {\n", f)
+			g.printf("combinator.Truncate(node, index)\nreturn 0, false, err\n} else {\noffset += n\n}\n")
+		}
+		g.printf("return offset, true, nil\n")
+	case repetition:
+		if e.min == 0 {
+			g.printf("offset := 0\n")
+		} else {
+			g.printf("index := len(node.GetChildren())\n")
+			g.printf("offset, ok, err := %s(node, data, parse)\n", names[0])
+			g.printf("if err != nil || !ok {\ncombinator.Truncate(node, index)\nreturn 0, false, err\n}\n")
+		}
+		g.printf("for {\nlength := len(node.GetChildren())\n")
+		g.printf("n, ok, err := %s(node, data[offset:], parse)\n", names[0])
+		if e.min == 0 {
+			g.printf("if err != nil {\nreturn 0, false, err\n}\n")
+		} else {
+			g.printf("if err != nil {\ncombinator.Truncate(node, index)\nreturn 0, false, err\n}\n")
+		}
+		g.printf("if !ok {\ncombinator.Truncate(node, length)\nreturn offset, true, nil\n}\n")
+		g.printf("offset += n\nif n == 0 {\nreturn offset, true, nil\n}\n}\n")
+	case optional:
+		g.printf("index := len(node.GetChildren())\n")
+		g.printf("n, ok, err := %s(node, data, parse)\n", names[0])
+		g.printf("if err != nil {\nreturn 0, false, err\n}\n")
+		g.printf("if !ok {\ncombinator.Truncate(node, index)\nreturn 0, true, nil\n}\nreturn n, true, nil\n")
+	case predicate:
+		g.printf("index := len(node.GetChildren())\n")
+		g.printf("_, ok, err := %s(node, data, parser.Quiet(parse))\ncombinator.Truncate(node, index)\n", names[0])
+		if e.not {
+			g.printf("return 0, !ok && err == nil, err\n")
+		} else {
+			g.printf("return 0, ok && err == nil, err\n")
+		}
+	case literal:
+		if len(e) == 0 {
+			g.printf("return 0, true, nil\n")
+			break
+		}
+		g.printf("const literal = %q\n", string(e))
+		g.printf("if len(data) >= len(literal) && string(data[:len(literal)]) == literal {\nreturn len(literal), true, nil\n}\n")
+		g.printf("if len(data) < len(literal) && string(data) == literal[:len(data)] && parser.More(parse, data) {\n")
+		g.printf("return 0, false, parser.ErrIncomplete\n}\n")
+		g.printf("parser.Expect(parse, data, %q)\nreturn 0, false, nil\n", strconv.Quote(string(e)))
+	case class:
+		g.rune("[" + e.String() + "]")
+		g.printf("if r, size := utf8.DecodeRune(data); %s {\nreturn size, true, nil\n}\n", e.condition())
+		g.printf("parser.Expect(parse, data, %q)\nreturn 0, false, nil\n", "["+e.String()+"]")
+	default:
+		g.rune("any rune")
+		g.printf("_, size := utf8.DecodeRune(data)\nreturn size, true, nil\n")
+	}
+	g.printf("}\n")
+	return name
+}
+
+func (g *generator) expressions(expressions []expression) []string {
+	names := make([]string, 0, len(expressions))
+	for _, e := range expressions {
+		names = append(names, g.expression(e))
+	}
+	return names
+}
+
+//rune writes check of the first rune of data, expected is recorded for empty data
+func (g *generator) rune(expected string) {
+	g.runes = true
+	g.printf("if !utf8.FullRune(data) && parser.More(parse, data) {\nreturn 0, false, parser.ErrIncomplete\n}\n")
+	g.printf("if len(data) == 0 {\nparser.Expect(parse, data, %q)\nreturn 0, false, nil\n}\n", expected)
+}
+
+func (g *generator) function(name string) {
+	g.printf("\nfunc %s%s {\n", name, signature)
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buffer, format, args...)
+}
+
+//condition returns Go expression matching rune r by class
+func (c class) condition() string {
+	conditions := make([]string, 0, len(c.ranges))
+	for _, r := range c.ranges {
+		if r[0] == r[1] {
+			conditions = append(conditions, fmt.Sprintf("r == %q", r[0]))
+			continue
+		}
+		conditions = append(conditions, fmt.Sprintf("r >= %q && r <= %q", r[0], r[1]))
+	}
+	if c.negate {
+		return fmt.Sprintf("!(%s)", strings.Join(conditions, " || "))
+	}
+	return strings.Join(conditions, " || ")
+}
+
+func ruleName(name string) string {
+	return "rule_" + name
+}
+
+func matchName(name string) string {
+	return "match_" + name
+}
@@ -0,0 +1,926 @@
+// Code generated by yaastr-gen. DO NOT EDIT.
+
+package example
+
+import (
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/biodebox/yaastr/parser/combinator"
+	"github.com/biodebox/yaastr/parser/peg"
+	"unicode/utf8"
+)
+
+// New returns parser of grammar
+func New() parser.Parser {
+	p := parser.New()
+	p.AddProcessorFor("(", Processor())
+	return p
+}
+
+// NewStrict returns parser of grammar which fails where start rule does not match
+func NewStrict() parser.Parser {
+	return parser.New(combinator.Expect(rule_List).Processor())
+}
+
+// Processor returns processor of start rule List
+func Processor() parser.Processor {
+	return combinator.Rule(rule_List).Processor()
+}
+
+// memoKey identifies rules in memo table of parser
+type memoKey string
+
+func expression1(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	const literal = "("
+	if len(data) >= len(literal) && string(data[:len(literal)]) == literal {
+		return len(literal), true, nil
+	}
+	if len(data) < len(literal) && string(data) == literal[:len(data)] && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	parser.Expect(parse, data, "\"(\"")
+	return 0, false, nil
+}
+
+func expression2(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	offset := 0
+	if n, ok, err := rule_Value(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := rule_spacing(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	return offset, true, nil
+}
+
+func expression3(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	offset := 0
+	for {
+		length := len(node.GetChildren())
+		n, ok, err := expression2(node, data[offset:], parse)
+		if err != nil {
+			return 0, false, err
+		}
+		if !ok {
+			combinator.Truncate(node, length)
+			return offset, true, nil
+		}
+		offset += n
+		if n == 0 {
+			return offset, true, nil
+		}
+	}
+}
+
+func expression4(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	const literal = ")"
+	if len(data) >= len(literal) && string(data[:len(literal)]) == literal {
+		return len(literal), true, nil
+	}
+	if len(data) < len(literal) && string(data) == literal[:len(data)] && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	parser.Expect(parse, data, "\")\"")
+	return 0, false, nil
+}
+
+func expression5(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	offset := 0
+	if n, ok, err := expression1(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := rule_spacing(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := expression3(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := expression4(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	return offset, true, nil
+}
+
+var first_List = [256]bool{0x28: true}
+
+func rule_List(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if len(data) > 0 && !first_List[data[0]] {
+		parser.Expect(parse, data, "List")
+		return 0, false, nil
+	}
+	return parser.Memoize(parse, memoKey("List"), node, data, match_List)
+}
+
+func match_List(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	child := peg.NewNode("List")
+	n, ok, err := parser.Descend(parse, child, data, expression5)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	if err := combinator.Attach(node, child, data[:n], parse); err != nil {
+		return 0, false, err
+	}
+	return n, true, nil
+}
+
+func expression6(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	if n, ok, err := rule_List(node, data, parse); err != nil {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else if ok {
+		return n, true, nil
+	}
+	combinator.Truncate(node, index)
+	if n, ok, err := rule_String(node, data, parse); err != nil {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else if ok {
+		return n, true, nil
+	}
+	combinator.Truncate(node, index)
+	if n, ok, err := rule_Number(node, data, parse); err != nil {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else if ok {
+		return n, true, nil
+	}
+	combinator.Truncate(node, index)
+	if n, ok, err := rule_Symbol(node, data, parse); err != nil {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else if ok {
+		return n, true, nil
+	}
+	combinator.Truncate(node, index)
+	return 0, false, nil
+}
+
+var first_Value = [256]bool{0x21: true, 0x22: true, 0x28: true, 0x2a: true, 0x2b: true, 0x2d: true, 0x2f: true, 0x30: true, 0x31: true, 0x32: true, 0x33: true, 0x34: true, 0x35: true, 0x36: true, 0x37: true, 0x38: true, 0x39: true, 0x3c: true, 0x3d: true, 0x3e: true, 0x3f: true, 0x41: true, 0x42: true, 0x43: true, 0x44: true, 0x45: true, 0x46: true, 0x47: true, 0x48: true, 0x49: true, 0x4a: true, 0x4b: true, 0x4c: true, 0x4d: true, 0x4e: true, 0x4f: true, 0x50: true, 0x51: true, 0x52: true, 0x53: true, 0x54: true, 0x55: true, 0x56: true, 0x57: true, 0x58: true, 0x59: true, 0x5a: true, 0x5f: true, 0x61: true, 0x62: true, 0x63: true, 0x64: true, 0x65: true, 0x66: true, 0x67: true, 0x68: true, 0x69: true, 0x6a: true, 0x6b: true, 0x6c: true, 0x6d: true, 0x6e: true, 0x6f: true, 0x70: true, 0x71: true, 0x72: true, 0x73: true, 0x74: true, 0x75: true, 0x76: true, 0x77: true, 0x78: true, 0x79: true, 0x7a: true}
+
+func rule_Value(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if len(data) > 0 && !first_Value[data[0]] {
+		parser.Expect(parse, data, "Value")
+		return 0, false, nil
+	}
+	return parser.Memoize(parse, memoKey("Value"), node, data, match_Value)
+}
+
+func match_Value(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	child := peg.NewNode("Value")
+	n, ok, err := parser.Descend(parse, child, data, expression6)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	if err := combinator.Attach(node, child, data[:n], parse); err != nil {
+		return 0, false, err
+	}
+	return n, true, nil
+}
+
+func expression7(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	const literal = "\""
+	if len(data) >= len(literal) && string(data[:len(literal)]) == literal {
+		return len(literal), true, nil
+	}
+	if len(data) < len(literal) && string(data) == literal[:len(data)] && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	parser.Expect(parse, data, "\"\\\"\"")
+	return 0, false, nil
+}
+
+func expression8(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if !utf8.FullRune(data) && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	if len(data) == 0 {
+		parser.Expect(parse, data, "[^\"\\\\]")
+		return 0, false, nil
+	}
+	if r, size := utf8.DecodeRune(data); !(r == '"' || r == '\\') {
+		return size, true, nil
+	}
+	parser.Expect(parse, data, "[^\"\\\\]")
+	return 0, false, nil
+}
+
+func expression9(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	if n, ok, err := rule_escape(node, data, parse); err != nil {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else if ok {
+		return n, true, nil
+	}
+	combinator.Truncate(node, index)
+	if n, ok, err := expression8(node, data, parse); err != nil {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else if ok {
+		return n, true, nil
+	}
+	combinator.Truncate(node, index)
+	return 0, false, nil
+}
+
+func expression10(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	offset := 0
+	for {
+		length := len(node.GetChildren())
+		n, ok, err := expression9(node, data[offset:], parse)
+		if err != nil {
+			return 0, false, err
+		}
+		if !ok {
+			combinator.Truncate(node, length)
+			return offset, true, nil
+		}
+		offset += n
+		if n == 0 {
+			return offset, true, nil
+		}
+	}
+}
+
+func expression11(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	const literal = "\""
+	if len(data) >= len(literal) && string(data[:len(literal)]) == literal {
+		return len(literal), true, nil
+	}
+	if len(data) < len(literal) && string(data) == literal[:len(data)] && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	parser.Expect(parse, data, "\"\\\"\"")
+	return 0, false, nil
+}
+
+func expression12(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	offset := 0
+	if n, ok, err := expression7(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := expression10(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := expression11(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	return offset, true, nil
+}
+
+var first_String = [256]bool{0x22: true}
+
+func rule_String(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if len(data) > 0 && !first_String[data[0]] {
+		parser.Expect(parse, data, "String")
+		return 0, false, nil
+	}
+	return parser.Memoize(parse, memoKey("String"), node, data, match_String)
+}
+
+func match_String(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	child := peg.NewNode("String")
+	n, ok, err := parser.Descend(parse, child, data, expression12)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	if err := combinator.Attach(node, child, data[:n], parse); err != nil {
+		return 0, false, err
+	}
+	return n, true, nil
+}
+
+func expression13(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	const literal = "\\"
+	if len(data) >= len(literal) && string(data[:len(literal)]) == literal {
+		return len(literal), true, nil
+	}
+	if len(data) < len(literal) && string(data) == literal[:len(data)] && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	parser.Expect(parse, data, "\"\\\\\"")
+	return 0, false, nil
+}
+
+func expression14(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if !utf8.FullRune(data) && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	if len(data) == 0 {
+		parser.Expect(parse, data, "[\"\\\\nt]")
+		return 0, false, nil
+	}
+	if r, size := utf8.DecodeRune(data); r == '"' || r == '\\' || r == 'n' || r == 't' {
+		return size, true, nil
+	}
+	parser.Expect(parse, data, "[\"\\\\nt]")
+	return 0, false, nil
+}
+
+func expression15(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	offset := 0
+	if n, ok, err := expression13(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := expression14(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	return offset, true, nil
+}
+
+func rule_escape(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if err := parser.Done(parse, data); err != nil {
+		return 0, false, err
+	}
+	return parser.Memoize(parse, memoKey("escape"), node, data, expression15)
+}
+
+func expression16(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	const literal = "-"
+	if len(data) >= len(literal) && string(data[:len(literal)]) == literal {
+		return len(literal), true, nil
+	}
+	if len(data) < len(literal) && string(data) == literal[:len(data)] && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	parser.Expect(parse, data, "\"-\"")
+	return 0, false, nil
+}
+
+func expression17(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	n, ok, err := expression16(node, data, parse)
+	if err != nil {
+		return 0, false, err
+	}
+	if !ok {
+		combinator.Truncate(node, index)
+		return 0, true, nil
+	}
+	return n, true, nil
+}
+
+func expression18(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if !utf8.FullRune(data) && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	if len(data) == 0 {
+		parser.Expect(parse, data, "[0-9]")
+		return 0, false, nil
+	}
+	if r, size := utf8.DecodeRune(data); r >= '0' && r <= '9' {
+		return size, true, nil
+	}
+	parser.Expect(parse, data, "[0-9]")
+	return 0, false, nil
+}
+
+func expression19(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	offset, ok, err := expression18(node, data, parse)
+	if err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	}
+	for {
+		length := len(node.GetChildren())
+		n, ok, err := expression18(node, data[offset:], parse)
+		if err != nil {
+			combinator.Truncate(node, index)
+			return 0, false, err
+		}
+		if !ok {
+			combinator.Truncate(node, length)
+			return offset, true, nil
+		}
+		offset += n
+		if n == 0 {
+			return offset, true, nil
+		}
+	}
+}
+
+func expression20(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	const literal = "."
+	if len(data) >= len(literal) && string(data[:len(literal)]) == literal {
+		return len(literal), true, nil
+	}
+	if len(data) < len(literal) && string(data) == literal[:len(data)] && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	parser.Expect(parse, data, "\".\"")
+	return 0, false, nil
+}
+
+func expression21(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if !utf8.FullRune(data) && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	if len(data) == 0 {
+		parser.Expect(parse, data, "[0-9]")
+		return 0, false, nil
+	}
+	if r, size := utf8.DecodeRune(data); r >= '0' && r <= '9' {
+		return size, true, nil
+	}
+	parser.Expect(parse, data, "[0-9]")
+	return 0, false, nil
+}
+
+func expression22(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	offset, ok, err := expression21(node, data, parse)
+	if err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	}
+	for {
+		length := len(node.GetChildren())
+		n, ok, err := expression21(node, data[offset:], parse)
+		if err != nil {
+			combinator.Truncate(node, index)
+			return 0, false, err
+		}
+		if !ok {
+			combinator.Truncate(node, length)
+			return offset, true, nil
+		}
+		offset += n
+		if n == 0 {
+			return offset, true, nil
+		}
+	}
+}
+
+func expression23(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	offset := 0
+	if n, ok, err := expression20(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := expression22(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	return offset, true, nil
+}
+
+func expression24(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	n, ok, err := expression23(node, data, parse)
+	if err != nil {
+		return 0, false, err
+	}
+	if !ok {
+		combinator.Truncate(node, index)
+		return 0, true, nil
+	}
+	return n, true, nil
+}
+
+func expression25(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if !utf8.FullRune(data) && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	if len(data) == 0 {
+		parser.Expect(parse, data, "[a-zA-Z_]")
+		return 0, false, nil
+	}
+	if r, size := utf8.DecodeRune(data); r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_' {
+		return size, true, nil
+	}
+	parser.Expect(parse, data, "[a-zA-Z_]")
+	return 0, false, nil
+}
+
+func expression26(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	_, ok, err := expression25(node, data, parser.Quiet(parse))
+	combinator.Truncate(node, index)
+	return 0, !ok && err == nil, err
+}
+
+func expression27(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	offset := 0
+	if n, ok, err := expression17(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := expression19(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := expression24(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := expression26(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	return offset, true, nil
+}
+
+var first_Number = [256]bool{0x2d: true, 0x30: true, 0x31: true, 0x32: true, 0x33: true, 0x34: true, 0x35: true, 0x36: true, 0x37: true, 0x38: true, 0x39: true}
+
+func rule_Number(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if len(data) > 0 && !first_Number[data[0]] {
+		parser.Expect(parse, data, "Number")
+		return 0, false, nil
+	}
+	return parser.Memoize(parse, memoKey("Number"), node, data, match_Number)
+}
+
+func match_Number(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	child := peg.NewNode("Number")
+	n, ok, err := parser.Descend(parse, child, data, expression27)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	if err := combinator.Attach(node, child, data[:n], parse); err != nil {
+		return 0, false, err
+	}
+	return n, true, nil
+}
+
+func expression28(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	_, ok, err := rule_Number(node, data, parser.Quiet(parse))
+	combinator.Truncate(node, index)
+	return 0, !ok && err == nil, err
+}
+
+func expression29(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if !utf8.FullRune(data) && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	if len(data) == 0 {
+		parser.Expect(parse, data, "[a-zA-Z_+\\-*/<>=!?]")
+		return 0, false, nil
+	}
+	if r, size := utf8.DecodeRune(data); r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_' || r == '+' || r == '-' || r == '*' || r == '/' || r == '<' || r == '>' || r == '=' || r == '!' || r == '?' {
+		return size, true, nil
+	}
+	parser.Expect(parse, data, "[a-zA-Z_+\\-*/<>=!?]")
+	return 0, false, nil
+}
+
+func expression30(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if !utf8.FullRune(data) && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	if len(data) == 0 {
+		parser.Expect(parse, data, "[a-zA-Z0-9_+\\-*/<>=!?]")
+		return 0, false, nil
+	}
+	if r, size := utf8.DecodeRune(data); r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '+' || r == '-' || r == '*' || r == '/' || r == '<' || r == '>' || r == '=' || r == '!' || r == '?' {
+		return size, true, nil
+	}
+	parser.Expect(parse, data, "[a-zA-Z0-9_+\\-*/<>=!?]")
+	return 0, false, nil
+}
+
+func expression31(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	offset := 0
+	for {
+		length := len(node.GetChildren())
+		n, ok, err := expression30(node, data[offset:], parse)
+		if err != nil {
+			return 0, false, err
+		}
+		if !ok {
+			combinator.Truncate(node, length)
+			return offset, true, nil
+		}
+		offset += n
+		if n == 0 {
+			return offset, true, nil
+		}
+	}
+}
+
+func expression32(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	offset := 0
+	if n, ok, err := expression28(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := expression29(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := expression31(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	return offset, true, nil
+}
+
+var first_Symbol = [256]bool{0x21: true, 0x2a: true, 0x2b: true, 0x2d: true, 0x2f: true, 0x3c: true, 0x3d: true, 0x3e: true, 0x3f: true, 0x41: true, 0x42: true, 0x43: true, 0x44: true, 0x45: true, 0x46: true, 0x47: true, 0x48: true, 0x49: true, 0x4a: true, 0x4b: true, 0x4c: true, 0x4d: true, 0x4e: true, 0x4f: true, 0x50: true, 0x51: true, 0x52: true, 0x53: true, 0x54: true, 0x55: true, 0x56: true, 0x57: true, 0x58: true, 0x59: true, 0x5a: true, 0x5f: true, 0x61: true, 0x62: true, 0x63: true, 0x64: true, 0x65: true, 0x66: true, 0x67: true, 0x68: true, 0x69: true, 0x6a: true, 0x6b: true, 0x6c: true, 0x6d: true, 0x6e: true, 0x6f: true, 0x70: true, 0x71: true, 0x72: true, 0x73: true, 0x74: true, 0x75: true, 0x76: true, 0x77: true, 0x78: true, 0x79: true, 0x7a: true}
+
+func rule_Symbol(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if len(data) > 0 && !first_Symbol[data[0]] {
+		parser.Expect(parse, data, "Symbol")
+		return 0, false, nil
+	}
+	return parser.Memoize(parse, memoKey("Symbol"), node, data, match_Symbol)
+}
+
+func match_Symbol(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	child := peg.NewNode("Symbol")
+	n, ok, err := parser.Descend(parse, child, data, expression32)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	if err := combinator.Attach(node, child, data[:n], parse); err != nil {
+		return 0, false, err
+	}
+	return n, true, nil
+}
+
+func expression33(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if !utf8.FullRune(data) && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	if len(data) == 0 {
+		parser.Expect(parse, data, "[ \t\r\n]")
+		return 0, false, nil
+	}
+	if r, size := utf8.DecodeRune(data); r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+		return size, true, nil
+	}
+	parser.Expect(parse, data, "[ \t\r\n]")
+	return 0, false, nil
+}
+
+func expression34(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	if n, ok, err := expression33(node, data, parse); err != nil {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else if ok {
+		return n, true, nil
+	}
+	combinator.Truncate(node, index)
+	if n, ok, err := rule_comment(node, data, parse); err != nil {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else if ok {
+		return n, true, nil
+	}
+	combinator.Truncate(node, index)
+	return 0, false, nil
+}
+
+func expression35(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	offset := 0
+	for {
+		length := len(node.GetChildren())
+		n, ok, err := expression34(node, data[offset:], parse)
+		if err != nil {
+			return 0, false, err
+		}
+		if !ok {
+			combinator.Truncate(node, length)
+			return offset, true, nil
+		}
+		offset += n
+		if n == 0 {
+			return offset, true, nil
+		}
+	}
+}
+
+func rule_spacing(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if err := parser.Done(parse, data); err != nil {
+		return 0, false, err
+	}
+	return parser.Memoize(parse, memoKey("spacing"), node, data, expression35)
+}
+
+func expression36(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	const literal = ";"
+	if len(data) >= len(literal) && string(data[:len(literal)]) == literal {
+		return len(literal), true, nil
+	}
+	if len(data) < len(literal) && string(data) == literal[:len(data)] && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	parser.Expect(parse, data, "\";\"")
+	return 0, false, nil
+}
+
+func expression37(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	const literal = "\n"
+	if len(data) >= len(literal) && string(data[:len(literal)]) == literal {
+		return len(literal), true, nil
+	}
+	if len(data) < len(literal) && string(data) == literal[:len(data)] && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	parser.Expect(parse, data, "\"\\n\"")
+	return 0, false, nil
+}
+
+func expression38(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	_, ok, err := expression37(node, data, parser.Quiet(parse))
+	combinator.Truncate(node, index)
+	return 0, !ok && err == nil, err
+}
+
+func expression39(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if !utf8.FullRune(data) && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	if len(data) == 0 {
+		parser.Expect(parse, data, "any rune")
+		return 0, false, nil
+	}
+	_, size := utf8.DecodeRune(data)
+	return size, true, nil
+}
+
+func expression40(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	offset := 0
+	if n, ok, err := expression38(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := expression39(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	return offset, true, nil
+}
+
+func expression41(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	offset := 0
+	for {
+		length := len(node.GetChildren())
+		n, ok, err := expression40(node, data[offset:], parse)
+		if err != nil {
+			return 0, false, err
+		}
+		if !ok {
+			combinator.Truncate(node, length)
+			return offset, true, nil
+		}
+		offset += n
+		if n == 0 {
+			return offset, true, nil
+		}
+	}
+}
+
+func expression42(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	const literal = "\n"
+	if len(data) >= len(literal) && string(data[:len(literal)]) == literal {
+		return len(literal), true, nil
+	}
+	if len(data) < len(literal) && string(data) == literal[:len(data)] && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	parser.Expect(parse, data, "\"\\n\"")
+	return 0, false, nil
+}
+
+func expression43(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if !utf8.FullRune(data) && parser.More(parse, data) {
+		return 0, false, parser.ErrIncomplete
+	}
+	if len(data) == 0 {
+		parser.Expect(parse, data, "any rune")
+		return 0, false, nil
+	}
+	_, size := utf8.DecodeRune(data)
+	return size, true, nil
+}
+
+func expression44(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	_, ok, err := expression43(node, data, parser.Quiet(parse))
+	combinator.Truncate(node, index)
+	return 0, !ok && err == nil, err
+}
+
+func expression45(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	if n, ok, err := expression42(node, data, parse); err != nil {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else if ok {
+		return n, true, nil
+	}
+	combinator.Truncate(node, index)
+	if n, ok, err := expression44(node, data, parse); err != nil {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else if ok {
+		return n, true, nil
+	}
+	combinator.Truncate(node, index)
+	return 0, false, nil
+}
+
+func expression46(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	_, ok, err := expression45(node, data, parser.Quiet(parse))
+	combinator.Truncate(node, index)
+	return 0, ok && err == nil, err
+}
+
+func expression47(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	index := len(node.GetChildren())
+	offset := 0
+	if n, ok, err := expression36(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := expression41(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	if n, ok, err := expression46(node, data[offset:], parse); err != nil || !ok {
+		combinator.Truncate(node, index)
+		return 0, false, err
+	} else {
+		offset += n
+	}
+	return offset, true, nil
+}
+
+func rule_comment(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, bool, error) {
+	if err := parser.Done(parse, data); err != nil {
+		return 0, false, err
+	}
+	return parser.Memoize(parse, memoKey("comment"), node, data, expression47)
+}
@@ -0,0 +1,159 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+//Package peg builds parsers from PEG grammars.
+//
+//Grammar consists of definitions "Name <- expression", the first one is the start rule. Expressions are
+//sequences, choices separated by /, prefixes & and !, suffixes ?, * and +, parentheses, literals in
+//single or double quotes, classes like [a-z] or [^"] and . matching any rune. Comments start with #.
+//Every rule which name starts with upper case letter creates Node, text matched by other rules belongs
+//to the enclosing node.
+package peg
+
+import (
+	"fmt"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/biodebox/yaastr/parser/combinator"
+	"strings"
+)
+
+type (
+	//Node is created for rules with exported names
+	Node struct {
+		*ast.Container
+		Name string
+	}
+	Grammar struct {
+		definitions []*definition
+		names       map[string]*definition
+		firsts      map[string]firstSet
+		nullables   map[string]bool
+	}
+	SyntaxError struct {
+		Text string
+	}
+	UndefinedError struct {
+		Name string
+	}
+	RedefinedError struct {
+		Name string
+	}
+	LeftRecursionError struct {
+		Rules []string
+	}
+	RangeError struct {
+		Low  rune
+		High rune
+	}
+)
+
+//NewNode
+func NewNode(name string) *Node {
+	return &Node{Container: ast.NewContainer(), Name: name}
+}
+
+//CloneNode
+func (n *Node) CloneNode() ast.Node {
+	return NewNode(n.Name)
+}
+
+//EqualNode
+func (n *Node) EqualNode(other ast.Node) bool {
+	return n.Name == other.(*Node).Name
+}
+
+//Error
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error near %q", e.Text)
+}
+
+//Error
+func (e *UndefinedError) Error() string {
+	return fmt.Sprintf("undefined rule %s", e.Name)
+}
+
+//Error
+func (e *RedefinedError) Error() string {
+	return fmt.Sprintf("rule %s redefined", e.Name)
+}
+
+//Error
+func (e *LeftRecursionError) Error() string {
+	return fmt.Sprintf("left recursion %s", strings.Join(e.Rules, " -> "))
+}
+
+//Error
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("invalid class range %q-%q", e.Low, e.High)
+}
+
+//Load parses and validates grammar, errors are returned as parser.ErrorList
+func Load(source []byte) (*Grammar, error) {
+	g, err := parse(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.check(source); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+//New builds parser for grammar
+func New(source []byte) (parser.Parser, error) {
+	g, err := Load(source)
+	if err != nil {
+		return nil, err
+	}
+	return g.Parser(), nil
+}
+
+//Parser returns parser which runs start definition only for input it may match
+func (g *Grammar) Parser() parser.Parser {
+	p := parser.New()
+	if set := g.first(g.definitions[0].name); set != nil {
+		p.AddProcessorFor(leading(set), g.Processor())
+	} else {
+		p.AddProcessor(g.Processor())
+	}
+	return p
+}
+
+//StrictParser returns parser which requires input to match start definition one match after another,
+//it fails with *parser.Error listing expected input where start definition does not match
+func (g *Grammar) StrictParser() parser.Parser {
+	return parser.New(combinator.Expect(g.Rule()).Processor())
+}
+
+//Rule returns rule of start definition, it creates nodes of named rules
+func (g *Grammar) Rule() combinator.Rule {
+	return g.compile()
+}
+
+//Processor returns processor of start definition
+func (g *Grammar) Processor() parser.Processor {
+	return g.Rule().Processor()
+}
@@ -0,0 +1,341 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package peg_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/biodebox/yaastr/parser/peg"
+	"github.com/biodebox/yaastr/parser/peg/internal/example"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func node(name string, children ...ast.Node) *peg.Node {
+	n := peg.NewNode(name)
+	n.AppendNode(children...)
+	return n
+}
+
+func text(content string) *ast.Text {
+	return ast.NewText([]byte(content)...)
+}
+
+func document(children ...ast.Node) *ast.Document {
+	return &ast.Document{Container: *ast.NewContainer(children...)}
+}
+
+func TestNew(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		grammar  string
+		input    string
+		expected *ast.Document
+	}{
+		{
+			name:     `hidden rules`,
+			grammar:  "Pair <- word '=' word\nword <- [a-z]+",
+			input:    `a=b c`,
+			expected: document(node(`Pair`, text(`a=b`)), text(` c`)),
+		},
+		{
+			name:     `nested`,
+			grammar:  "List <- '(' (Word / List)* ')'\nWord <- [a-z]",
+			input:    `(a(b))`,
+			expected: document(node(`List`, text(`(`), node(`Word`, text(`a`)), node(`List`, text(`(`), node(`Word`, text(`b`)), text(`)`)), text(`)`))),
+		},
+		{
+			name:     `escapes`,
+			grammar:  `Quote <- '\'' [^'\\]* '\'' / "\"" [\]\-^]+ "\""`,
+			input:    `'it' "]-^"`,
+			expected: document(node(`Quote`, text(`'it'`)), text(` `), node(`Quote`, text(`"]-^"`))),
+		},
+		{
+			name:     `predicates`,
+			grammar:  "Keyword <- 'if' ![a-z] / 'else' &' '",
+			input:    `iffy if else else`,
+			expected: document(text(`iffy `), node(`Keyword`, text(`if`)), text(` `), node(`Keyword`, text(`else`)), text(` else`)),
+		},
+		{
+			name:     `optional and any`,
+			grammar:  "Signed <- '-'? Digit+\nDigit <- [0-9]\nComment <- '#' .",
+			input:    `-12 3`,
+			expected: document(node(`Signed`, text(`-`), node(`Digit`, text(`1`)), node(`Digit`, text(`2`))), text(` `), node(`Signed`, node(`Digit`, text(`3`)))),
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := peg.New([]byte(test.grammar))
+			if !assert.NoError(t, err) {
+				return
+			}
+			actual, err := p.Parse([]byte(test.input))
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.True(t, ast.Equal(test.expected, actual), ast.Diff(test.expected, actual))
+			buffer := &bytes.Buffer{}
+			if assert.NoError(t, ast.Render(buffer, actual)) {
+				assert.Equal(t, test.input, buffer.String())
+			}
+		})
+	}
+	t.Run(`spans`, func(t *testing.T) {
+		p, err := peg.New([]byte("List <- '(' (Word / List)* ')'\nWord <- [a-z]"))
+		if !assert.NoError(t, err) {
+			return
+		}
+		actual, err := p.Parse([]byte(`x(a(b))`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		list := actual.(*ast.Document).Children[1].(*peg.Node)
+		assert.Equal(t, ast.Span{Start: 1, End: 7}, list.Span)
+		assert.Equal(t, ast.Span{Start: 3, End: 6}, list.Children[2].(*peg.Node).Span)
+	})
+}
+
+func TestLoad(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		grammar string
+		err     string
+		target  interface{}
+	}{
+		{name: `empty`, grammar: "# nothing\n", err: `1:1: empty grammar`},
+		{name: `syntax`, grammar: "A <- 'a'\nB <- 'b' )\n", err: `2:10: syntax error near ")"`, target: new(*peg.SyntaxError)},
+		{name: `unterminated literal`, grammar: "A <- 'a", err: `1:6: syntax error near "'a"`, target: new(*peg.SyntaxError)},
+		{name: `undefined`, grammar: "A <- 'a' B", err: `1:10: undefined rule B`, target: new(*peg.UndefinedError)},
+		{name: `redefined`, grammar: "A <- 'a'\nA <- 'b'", err: `2:1: rule A redefined`, target: new(*peg.RedefinedError)},
+		{name: `left recursion`, grammar: "A <- A 'a' / 'a'", err: `1:1: left recursion A -> A`, target: new(*peg.LeftRecursionError)},
+		{name: `predicate left recursion`, grammar: "A <- 'a' / B\nB <- !A 'b'", err: `1:1: left recursion A -> B -> A`, target: new(*peg.LeftRecursionError)},
+		{
+			name:    `indirect left recursion`,
+			grammar: "A <- B 'a'\nB <- c? C\nC <- 'c'* A\nc <- 'c'",
+			err:     `1:1: left recursion A -> B -> C -> A`,
+			target:  new(*peg.LeftRecursionError),
+		},
+		{name: `reversed range`, grammar: "A <- 'a' [a-cz-x]", err: `1:10: invalid class range 'z'-'x'`, target: new(*peg.RangeError)},
+		{name: `several errors`, grammar: "A <- B C", err: `1:6: undefined rule B (and 1 more errors)`, target: new(*peg.UndefinedError)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := peg.Load([]byte(test.grammar))
+			if !assert.EqualError(t, err, test.err) {
+				return
+			}
+			assert.IsType(t, parser.ErrorList{}, err)
+			if test.target != nil {
+				assert.True(t, errors.As(err, test.target))
+			}
+		})
+	}
+	t.Run(`not left recursion`, func(t *testing.T) {
+		_, err := peg.Load([]byte("A <- 'a' A / B\nB <- 'b' !A B?"))
+		assert.NoError(t, err)
+	})
+}
+
+func TestGrammar_Generate(t *testing.T) {
+	source, err := ioutil.ReadFile(`internal/example/example.peg`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	grammar, err := peg.Load(source)
+	if !assert.NoError(t, err) {
+		return
+	}
+	t.Run(`generated`, func(t *testing.T) {
+		expected, err := ioutil.ReadFile(`internal/example/example.go`)
+		if !assert.NoError(t, err) {
+			return
+		}
+		buffer := &bytes.Buffer{}
+		if assert.NoError(t, grammar.Generate(buffer, `example`)) {
+			assert.Equal(t, string(expected), buffer.String(), `run go generate ./...`)
+		}
+	})
+	interpreted := parser.New(grammar.Processor())
+	for _, input := range []string{
+		``,
+		`(define (square x) (* x x))`,
+		`(list "a \"quoted\" \\ string" -1.5 2 x2 ; comment` + "\n" + `)`,
+		`((1a) (- 1) ( ) "unterminated)`,
+		`text (ключ "значение") ; no list`,
+	} {
+		t.Run(input, func(t *testing.T) {
+			expected, err := interpreted.Parse([]byte(input))
+			if !assert.NoError(t, err) {
+				return
+			}
+			actual, err := example.New().Parse([]byte(input))
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.True(t, ast.Equal(expected, actual), ast.Diff(expected, actual))
+			assert.Equal(t, expected, actual)
+			actual, err = example.New().ParseReader(iotest.OneByteReader(strings.NewReader(input)))
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestGrammar_StrictParser(t *testing.T) {
+	source, err := ioutil.ReadFile(`internal/example/example.peg`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	grammar, err := peg.Load(source)
+	if !assert.NoError(t, err) {
+		return
+	}
+	tests := []struct {
+		input    string
+		offset   int
+		expected []string
+	}{
+		{input: `(a "b`, offset: 5, expected: []string{`"\\"`, `[^"\\]`, `"\""`}},
+		{input: `(a 1.x)`, offset: 5, expected: []string{`[0-9]`}},
+		{input: `(a) x`, offset: 3, expected: []string{`List`}},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			for name, p := range map[string]parser.Parser{`interpreted`: grammar.StrictParser(), `generated`: example.NewStrict()} {
+				_, err := p.Parse([]byte(test.input))
+				var parseError *parser.Error
+				if assert.True(t, errors.As(err, &parseError), name) {
+					assert.Equal(t, test.offset, parseError.Offset, name)
+					assert.Equal(t, test.expected, parseError.Expected, name)
+				}
+			}
+		})
+	}
+	t.Run(`valid`, func(t *testing.T) {
+		_, err := grammar.StrictParser().Parse([]byte(`(a "b" (1.5))`))
+		assert.NoError(t, err)
+	})
+}
+
+func TestGrammar_Memo(t *testing.T) {
+	grammar, err := peg.Load([]byte("A <- B 'x' / B 'y' / B\nB <- '(' A ')' / 'z'\n"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	parse := func(depth int) (ast.Node, *parser.MemoStats) {
+		stats := &parser.MemoStats{}
+		p := grammar.Parser()
+		p.SetOptions(parser.Memo(stats))
+		node, err := p.Parse([]byte(strings.Repeat(`(`, depth) + `z` + strings.Repeat(`)`, depth)))
+		assert.NoError(t, err)
+		return node, stats
+	}
+	expected, err := grammar.Parser().Parse([]byte(`(((z)))`))
+	if !assert.NoError(t, err) {
+		return
+	}
+	actual, stats := parse(3)
+	assert.True(t, ast.Equal(expected, actual), ast.Diff(expected, actual))
+	assert.True(t, stats.Hits > 0)
+	_, small := parse(50)
+	_, large := parse(100)
+	assert.True(t, small.Misses > 0)
+	assert.True(t, large.Misses <= 2*small.Misses+2, `%d misses at depth 100, %d at depth 50`, large.Misses, small.Misses)
+	t.Run(`generated`, func(t *testing.T) {
+		stats := &parser.MemoStats{}
+		p := example.New()
+		p.SetOptions(parser.Memo(stats))
+		input := []byte(`(define (square x) (* x x))`)
+		actual, err := p.Parse(input)
+		if !assert.NoError(t, err) {
+			return
+		}
+		expected, err := example.New().Parse(input)
+		if assert.NoError(t, err) {
+			assert.True(t, ast.Equal(expected, actual), ast.Diff(expected, actual))
+		}
+		assert.True(t, stats.Misses > 0)
+	})
+}
+
+func TestGrammar_Limits(t *testing.T) {
+	t.Run(`max depth`, func(t *testing.T) {
+		grammar, err := peg.Load([]byte("List <- '(' List* ')'\n"))
+		if !assert.NoError(t, err) {
+			return
+		}
+		input := []byte(strings.Repeat(`(`, 1000) + strings.Repeat(`)`, 1000))
+		for name, p := range map[string]parser.Parser{`interpreted`: grammar.Parser(), `generated`: example.New()} {
+			t.Run(name, func(t *testing.T) {
+				p.SetOptions(parser.MaxDepth(50))
+				_, err := p.Parse(input)
+				assert.True(t, errors.Is(err, parser.ErrMaxDepth), `%v`, err)
+			})
+		}
+	})
+	t.Run(`context`, func(t *testing.T) {
+		grammar, err := peg.Load([]byte("A <- B 'x' / B 'y' / B\nB <- '(' A ')' / 'z'\n"))
+		if !assert.NoError(t, err) {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err = grammar.Parser().ParseContext(ctx, []byte(strings.Repeat(`(`, 30)+`z`+strings.Repeat(`)`, 30)))
+		assert.True(t, errors.Is(err, context.DeadlineExceeded), `%v`, err)
+		assert.True(t, time.Since(start) < time.Second, `%s`, time.Since(start))
+	})
+}
+
+func BenchmarkGrammar(b *testing.B) {
+	source, err := ioutil.ReadFile(`internal/example/example.peg`)
+	if err != nil {
+		b.Fatal(err)
+	}
+	interpreted, err := peg.New(source)
+	if err != nil {
+		b.Fatal(err)
+	}
+	input := []byte(strings.Repeat(`(define (square x) (* x x)) "text" `, 10000))
+	for name, p := range map[string]parser.Parser{`interpreted`: interpreted, `generated`: example.New()} {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := p.Parse(input); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
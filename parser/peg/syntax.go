@@ -0,0 +1,305 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package peg
+
+import (
+	"bytes"
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/biodebox/yaastr/parser/combinator"
+	"strings"
+	"unicode/utf8"
+)
+
+type (
+	definition struct {
+		name       string
+		expression expression
+		span       ast.Span
+	}
+	expression interface{}
+	choice     []expression
+	sequence   []expression
+	repetition struct {
+		expression
+		min int
+	}
+	optional struct {
+		expression
+	}
+	predicate struct {
+		expression
+		not bool
+	}
+	reference struct {
+		name string
+		span ast.Span
+	}
+	literal string
+	class   struct {
+		ranges [][2]rune
+		negate bool
+		span   ast.Span
+	}
+	anyRune struct{}
+)
+
+//names of nodes created by grammar syntax
+const (
+	definitionNode = "Definition"
+	spacingNode    = "Spacing"
+	identifierNode = "Identifier"
+	choiceNode     = "Choice"
+	sequenceNode   = "Sequence"
+	prefixNode     = "Prefix"
+	suffixNode     = "Suffix"
+	operatorNode   = "Operator"
+	literalNode    = "Literal"
+	classNode      = "Class"
+	anyNode        = "Any"
+)
+
+//ErrEmpty is returned for grammar without definitions
+var ErrEmpty = errors.New("empty grammar")
+
+//syntax returns rule of grammar definitions and spacing between them
+func syntax() combinator.Rule {
+	var expression combinator.Rule
+	char := func(ending string) combinator.Rule {
+		return combinator.Choice(
+			combinator.Seq(combinator.Literal(`\`), combinator.Any()),
+			combinator.Seq(combinator.Not(combinator.Literal(ending)), combinator.Any()),
+		)
+	}
+	comment := combinator.Seq(combinator.Literal(`#`), combinator.Many(combinator.CharClass("^\n")))
+	spacing := combinator.Many(combinator.Choice(combinator.CharClass(" \t\r\n"), comment))
+	identifier := combinator.Seq(combinator.Map(
+		combinator.Seq(combinator.CharClass(`a-zA-Z_`), combinator.Many(combinator.CharClass(`a-zA-Z0-9_`))),
+		nodeFactory(identifierNode),
+	), spacing)
+	literal := combinator.Seq(combinator.Map(combinator.Choice(
+		combinator.Seq(combinator.Literal(`'`), combinator.Many(char(`'`)), combinator.Literal(`'`)),
+		combinator.Seq(combinator.Literal(`"`), combinator.Many(char(`"`)), combinator.Literal(`"`)),
+	), nodeFactory(literalNode)), spacing)
+	class := combinator.Seq(combinator.Map(combinator.Seq(
+		combinator.Literal(`[`),
+		combinator.Optional(combinator.Literal(`^`)),
+		combinator.Many1(combinator.Seq(char(`]`), combinator.Optional(combinator.Seq(combinator.Literal(`-`), char(`]`))))),
+		combinator.Literal(`]`),
+	), nodeFactory(classNode)), spacing)
+	primary := combinator.Choice(
+		combinator.Seq(identifier, combinator.Not(combinator.Literal(`<-`))),
+		combinator.Seq(combinator.Literal(`(`), spacing, combinator.Ref(&expression), combinator.Literal(`)`), spacing),
+		literal,
+		class,
+		combinator.Seq(combinator.Map(combinator.Literal(`.`), nodeFactory(anyNode)), spacing),
+	)
+	operator := func(class string) combinator.Rule {
+		return combinator.Seq(combinator.Map(combinator.CharClass(class), nodeFactory(operatorNode)), spacing)
+	}
+	suffix := combinator.Map(combinator.Seq(primary, combinator.Optional(operator(`?*+`))), nodeFactory(suffixNode))
+	prefix := combinator.Map(combinator.Seq(combinator.Optional(operator(`&!`)), suffix), nodeFactory(prefixNode))
+	sequence := combinator.Map(combinator.Many(prefix), nodeFactory(sequenceNode))
+	alternative := combinator.Seq(combinator.Literal(`/`), spacing, sequence)
+	expression = combinator.Map(combinator.Seq(sequence, combinator.Many(alternative)), nodeFactory(choiceNode))
+	return combinator.Choice(
+		combinator.Map(combinator.Seq(identifier, combinator.Literal(`<-`), spacing, expression), nodeFactory(definitionNode)),
+		combinator.Map(combinator.Many1(combinator.Choice(combinator.CharClass(" \t\r\n"), comment)), nodeFactory(spacingNode)),
+	)
+}
+
+//parse builds grammar definitions from source, unmatched text is a syntax error
+func parse(source []byte) (*Grammar, error) {
+	node, err := parser.New(syntax().Processor()).Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	g := &Grammar{}
+	for _, child := range node.(ast.ParentNode).GetChildren() {
+		switch child := child.(type) {
+		case *Node:
+			if child.Name == definitionNode {
+				g.definitions = append(g.definitions, newDefinition(child))
+			}
+		case *ast.Text:
+			text := string(child.Content)
+			if index := strings.IndexByte(text, '\n'); index >= 0 {
+				text = text[:index]
+			}
+			return nil, newErrorList(source, child.Span.Start, &SyntaxError{Text: text})
+		}
+	}
+	if len(g.definitions) == 0 {
+		return nil, newErrorList(source, 0, ErrEmpty)
+	}
+	return g, nil
+}
+
+func newDefinition(node *Node) *definition {
+	nodes := children(node)
+	return &definition{name: content(nodes[0]), span: nodes[0].Span, expression: newExpression(nodes[1])}
+}
+
+func newExpression(node *Node) expression {
+	nodes := children(node)
+	switch node.Name {
+	case choiceNode, sequenceNode:
+		expressions := make([]expression, 0, len(nodes))
+		for _, child := range nodes {
+			expressions = append(expressions, newExpression(child))
+		}
+		if len(expressions) == 1 {
+			return expressions[0]
+		}
+		if node.Name == choiceNode {
+			return choice(expressions)
+		}
+		return sequence(expressions)
+	case prefixNode:
+		if nodes[0].Name != operatorNode {
+			return newExpression(nodes[0])
+		}
+		return predicate{expression: newExpression(nodes[1]), not: content(nodes[0]) == `!`}
+	case suffixNode:
+		e := newExpression(nodes[0])
+		if len(nodes) == 1 {
+			return e
+		}
+		switch content(nodes[1]) {
+		case `?`:
+			return optional{expression: e}
+		case `+`:
+			return repetition{expression: e, min: 1}
+		}
+		return repetition{expression: e}
+	case identifierNode:
+		return reference{name: content(node), span: node.Span}
+	case literalNode:
+		value := content(node)
+		return literal(unescape(value[1 : len(value)-1]))
+	case classNode:
+		c := newClass(content(node))
+		c.span = node.Span
+		return c
+	}
+	return anyRune{}
+}
+
+//newClass decodes class like [^a-z\]]
+func newClass(value string) class {
+	c := class{}
+	value = value[1 : len(value)-1]
+	if strings.HasPrefix(value, `^`) {
+		c.negate = true
+		value = value[1:]
+	}
+	for len(value) > 0 {
+		low, size := unescapeRune(value)
+		value = value[size:]
+		high := low
+		if len(value) > 1 && value[0] == '-' {
+			high, size = unescapeRune(value[1:])
+			value = value[1+size:]
+		}
+		c.ranges = append(c.ranges, [2]rune{low, high})
+	}
+	return c
+}
+
+//String returns class in combinator.CharClass syntax
+func (c class) String() string {
+	builder := &strings.Builder{}
+	if c.negate {
+		builder.WriteByte('^')
+	}
+	for _, r := range c.ranges {
+		writeClassRune(builder, r[0])
+		if r[1] != r[0] {
+			builder.WriteByte('-')
+			writeClassRune(builder, r[1])
+		}
+	}
+	return builder.String()
+}
+
+func writeClassRune(builder *strings.Builder, r rune) {
+	if strings.ContainsRune(`\-^`, r) {
+		builder.WriteByte('\\')
+	}
+	builder.WriteRune(r)
+}
+
+func unescape(value string) string {
+	builder := &strings.Builder{}
+	for len(value) > 0 {
+		r, size := unescapeRune(value)
+		builder.WriteRune(r)
+		value = value[size:]
+	}
+	return builder.String()
+}
+
+//unescapeRune decodes first rune of value, \n, \r and \t are control characters, other escaped runes are literal
+func unescapeRune(value string) (rune, int) {
+	if value[0] != '\\' || len(value) == 1 {
+		return utf8.DecodeRuneInString(value)
+	}
+	r, size := utf8.DecodeRuneInString(value[1:])
+	switch r {
+	case 'n':
+		r = '\n'
+	case 'r':
+		r = '\r'
+	case 't':
+		r = '\t'
+	}
+	return r, size + 1
+}
+
+func children(node ast.ParentNode) []*Node {
+	var nodes []*Node
+	for _, child := range node.GetChildren() {
+		if child, ok := child.(*Node); ok {
+			nodes = append(nodes, child)
+		}
+	}
+	return nodes
+}
+
+func content(node ast.Node) string {
+	buffer := &bytes.Buffer{}
+	_ = ast.Render(buffer, node)
+	return buffer.String()
+}
+
+func newErrorList(source []byte, offset int, err error) parser.ErrorList {
+	return parser.ErrorList{newError(source, offset, err)}
+}
+
+func newError(source []byte, offset int, err error) *parser.Error {
+	return &parser.Error{Position: ast.PositionOf(source, offset), Err: err}
+}
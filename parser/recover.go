@@ -0,0 +1,85 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"unicode/utf8"
+)
+
+//recover replaces data up to sync point with ast.Error node when err is returned by processor and Recover option is set.
+//It returns ErrIncomplete when the sync point may follow in the next input.
+func (b *builder) recover(data []byte, err error) (int, error) {
+	var parseError *Error
+	if !b.recovery || !errors.As(err, &parseError) || parseError.Processor == nil || errors.Is(err, ErrIncomplete) && !b.eof {
+		return 0, err
+	}
+	length := b.sync(data)
+	if length == len(data) && b.more(data) {
+		return 0, ErrIncomplete
+	}
+	b.rollback()
+	b.errors.Add(parseError)
+	content := data[:length:length]
+	if b.copyText {
+		content = append([]byte(nil), content...)
+	}
+	b.node.AppendNode(ast.NewError(parseError, content...))
+	return length, nil
+}
+
+//sync returns length of data before the nearest sync point, at least one rune is skipped
+func (s *state) sync(data []byte) int {
+	_, length := utf8.DecodeRune(data)
+	if len(s.syncPoints) == 0 {
+		return length
+	}
+	end := len(data)
+	for _, point := range s.syncPoints {
+		if index := bytes.Index(data[length:], point); index >= 0 && length+index < end {
+			end = length + index
+		}
+	}
+	return end
+}
+
+//result adds err to recovered errors
+func (s *state) result(err error) error {
+	if len(s.errors) == 0 {
+		return err
+	}
+	if err != nil {
+		var parseError *Error
+		if !errors.As(err, &parseError) {
+			parseError = &Error{Position: s.position(s.base + len(s.input)), Err: err}
+		}
+		s.errors.Add(parseError)
+	}
+	s.errors.Sort()
+	return s.errors
+}
@@ -0,0 +1,106 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func errorNode(err error, content string, start int) *ast.Error {
+	return span(ast.NewError(err, []byte(content)...), start, start+len(content)).(*ast.Error)
+}
+
+func TestRecover(t *testing.T) {
+	data := []byte("a \"b 'c\nd\" 'e\nf")
+	p := parser.New(processorQuote(), processorDoubleQuote(parser.KeepDelimiters()))
+	p.SetOptions(parser.Recover("\n"))
+	t.Run(`parse`, func(t *testing.T) {
+		node, err := p.Parse(data)
+		var list parser.ErrorList
+		if !assert.True(t, errors.As(err, &list)) || !assert.Len(t, list, 2) {
+			return
+		}
+		for i, offset := range []int{5, 11} {
+			assert.Equal(t, offset, list[i].Offset)
+			assert.Equal(t, &parser.UnterminatedError{Opening: `'`}, list[i].Err)
+		}
+		assert.EqualError(t, err, `1:6: unterminated "'" (and 1 more errors)`)
+		doc := document(len(data))
+		inner := span(&quote{Container: ast.NewContainer(), double: true}, 2, 10).(*quote)
+		inner.SetDelimiters([]byte(`"`), []byte(`"`))
+		inner.AppendNode(text(`b `, 3), errorNode(list[0], `'c`, 5), text("\nd", 7))
+		doc.AppendNode(text(`a `, 0), inner, text(` `, 10), errorNode(list[1], `'e`, 11), text("\nf", 13))
+		assert.True(t, ast.Equal(doc, node), ast.Diff(doc, node))
+		var buffer bytes.Buffer
+		if assert.NoError(t, ast.Render(&buffer, node)) {
+			assert.Equal(t, string(data), buffer.String())
+		}
+	})
+	t.Run(`reader`, func(t *testing.T) {
+		expected, expectedErr := p.Parse(data)
+		node, err := p.ParseReader(iotest.OneByteReader(bytes.NewReader(data)))
+		assert.True(t, ast.Equal(expected, node), ast.Diff(expected, node))
+		assert.Equal(t, expectedErr.Error(), err.Error())
+	})
+	t.Run(`without sync points`, func(t *testing.T) {
+		p := parser.New(processorQuote())
+		p.SetOptions(parser.Recover())
+		node, err := p.Parse([]byte(`'ab`))
+		var list parser.ErrorList
+		if !assert.True(t, errors.As(err, &list)) || !assert.Len(t, list, 1) {
+			return
+		}
+		doc := document(3)
+		doc.AppendNode(errorNode(list[0], `'`, 0), text(`ab`, 1))
+		assert.True(t, ast.Equal(doc, node), ast.Diff(doc, node))
+	})
+	t.Run(`without errors`, func(t *testing.T) {
+		_, err := p.Parse([]byte(`'a'`))
+		assert.NoError(t, err)
+	})
+	t.Run(`fatal`, func(t *testing.T) {
+		p := parser.New(processorQuote(), processorDoubleQuote())
+		p.SetOptions(parser.Recover(), parser.MaxDepth(1))
+		_, err := p.Parse([]byte(`'b "a"`))
+		var list parser.ErrorList
+		if assert.True(t, errors.As(err, &list)) && assert.Len(t, list, 2) {
+			assert.Equal(t, 0, list[0].Offset)
+			assert.True(t, errors.Is(list[1], parser.ErrMaxDepth))
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = p.ParseContext(ctx, []byte(strings.Repeat(`a`, 10)))
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+}
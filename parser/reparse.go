@@ -0,0 +1,263 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser
+
+import (
+	"context"
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"sync"
+)
+
+type (
+	//Edit replaces old input from Start to OldEnd with new input from Start to NewEnd
+	Edit struct {
+		Start  int
+		OldEnd int
+		NewEnd int
+	}
+	//origin is rule which created top level nodes at start
+	origin struct {
+		start int
+		rule  *rule
+	}
+	//history keeps origins of the latest tree built by parser with Incremental option
+	history struct {
+		mutex     sync.Mutex
+		doc       *ast.Document
+		origins   []origin
+		lookahead int
+	}
+)
+
+var ErrInvalidEdit = errors.New("invalid edit")
+
+//Reparse parses data which is old input changed by edit and returns tree equal to Parse of data.
+//Top level nodes of old tree after the edit are reused, processors run from the start of data until the edit
+//and then until parsing meets a node boundary of old tree again.
+//With Incremental option top level nodes of the latest tree built by the parser are reused before the edit too,
+//when they end at least lookahead bytes before it and no processor run before their processor matches data at their position.
+//Old tree must be returned by Parse, ParseReader or Reparse of old input with the same parser, its nodes are moved to the result.
+//Reused text shares content with old input, so old input must not be modified.
+//Old tree without spans and Recover option cause full parse.
+func (p *parser) Reparse(old ast.Node, data []byte, edit Edit) (ast.Node, error) {
+	doc, ok := old.(*ast.Document)
+	if !ok || p.recovery || !hasSpans(doc.Children) {
+		return p.Parse(data)
+	}
+	length := doc.Span.End
+	if edit.Start < 0 || edit.Start > edit.OldEnd || edit.OldEnd > length || edit.Start > edit.NewEnd ||
+		len(data) != length-edit.OldEnd+edit.NewEnd {
+		return nil, ErrInvalidEdit
+	}
+	result := &ast.Document{}
+	result.SetSpan(ast.Span{End: len(data)})
+	s := p.newState(context.Background(), data, nil)
+	defer s.finish()
+	if p.maxInput > 0 && len(data) > p.maxInput {
+		return result, s.error(p.maxInput, nil, ErrMaxInputSize)
+	}
+	err := s.reparse(result, data, doc.Children, p.latest.get(doc), edit)
+	p.latest.set(result, s.origins)
+	return result, err
+}
+
+//reparse is top level parse which takes nodes created for unchanged input from old children,
+//origins of old children are needed to reuse them before the edit
+func (s *state) reparse(doc *ast.Document, data []byte, old []ast.Node, origins []origin, edit Edit) error {
+	s.path = append(s.path, doc)
+	shift := edit.NewEnd - edit.OldEnd
+	b := s.builder(doc, data)
+	index, next := 0, 0
+	//nodes before the edit must end lookahead bytes before it
+	limit := edit.Start
+	if s.latest != nil {
+		limit -= s.latest.lookahead
+	}
+	for len(data) > 0 {
+		position := s.offset(data)
+		if position < edit.Start {
+			index = seek(old, index, position)
+			next = seekOrigin(origins, next, position)
+			if end := reusable(old, index, position, limit); end > index && next < len(origins) && origins[next].start == position {
+				//rules before the origin failed at position in old input, they must fail in data too
+				if rules, ok := earlier(s.current().candidates(data[0]), origins[next].rule); ok {
+					offset, err := s.apply(rules, doc, data)
+					if err != nil {
+						return err
+					}
+					if offset == 0 {
+						length := spanOf(old[index]).Len()
+						if err := b.reuse(old[index:end], 0); err != nil {
+							return err
+						}
+						s.origins = append(s.origins, origins[next])
+						data = data[length:]
+						index = end
+						continue
+					}
+					if err := b.consume(data, offset); err != nil {
+						return err
+					}
+					data = data[offset:]
+					continue
+				}
+			}
+		} else if position >= edit.NewEnd {
+			index = seek(old, index, position-shift)
+			if index < len(old) && boundary(old, index, position-shift) && (len(b.text) == 0 || !isText(old[index])) {
+				if err := b.reuse(old[index:], shift); err != nil {
+					return err
+				}
+				for _, o := range origins[seekOrigin(origins, next, position-shift):] {
+					s.origins = append(s.origins, origin{start: o.start + shift, rule: o.rule})
+				}
+				return nil
+			}
+		}
+		offset, err := b.step(data)
+		if err != nil {
+			return err
+		}
+		data = data[offset:]
+	}
+	return b.flush()
+}
+
+//reuse appends old nodes moved by shift after pending text
+func (b *builder) reuse(nodes []ast.Node, shift int) error {
+	if err := b.flush(); err != nil {
+		return err
+	}
+	count := 0
+	for _, node := range nodes {
+		ast.Inspect(node, func(node ast.Node) bool {
+			if spanned, ok := node.(ast.Spanned); ok && shift != 0 {
+				span := spanned.GetSpan()
+				spanned.SetSpan(ast.Span{Start: span.Start + shift, End: span.End + shift})
+			}
+			count++
+			return true
+		})
+	}
+	//nodes are copied, so appending to the result never writes into array of old children
+	b.node.AppendNode(append([]ast.Node(nil), nodes...)...)
+	b.index = len(b.node.GetChildren())
+	return b.count(count, spanOf(nodes[0]).Start)
+}
+
+//seek returns index of the first node which does not start before position
+func seek(nodes []ast.Node, index, position int) int {
+	for index < len(nodes) && spanOf(nodes[index]).Start < position {
+		index++
+	}
+	return index
+}
+
+//reusable returns end of nodes created by one processor call at position which end not after limit
+func reusable(nodes []ast.Node, index, position, limit int) int {
+	if index == len(nodes) || !boundary(nodes, index, position) {
+		return index
+	}
+	span := spanOf(nodes[index])
+	if span.End > limit {
+		return index
+	}
+	end := index
+	for end < len(nodes) && spanOf(nodes[end]) == span && !isText(nodes[end]) {
+		end++
+	}
+	if end < len(nodes) && spanOf(nodes[end]).Start < span.End {
+		return index
+	}
+	return end
+}
+
+//seekOrigin returns index of the first origin which does not start before position
+func seekOrigin(origins []origin, index, position int) int {
+	for index < len(origins) && origins[index].start < position {
+		index++
+	}
+	return index
+}
+
+//earlier returns rules preceding r, ok is false when rules do not contain r
+func earlier(rules []*rule, r *rule) ([]*rule, bool) {
+	for index, candidate := range rules {
+		if candidate == r {
+			return rules[:index], true
+		}
+	}
+	return nil, false
+}
+
+//set records origins of top level nodes of doc, it does nothing without Incremental option
+func (h *history) set(doc *ast.Document, origins []origin) {
+	if h == nil {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.doc, h.origins = doc, origins
+}
+
+//get returns origins of top level nodes of doc when it is the latest tree
+func (h *history) get(doc *ast.Document) []origin {
+	if h == nil {
+		return nil
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.doc != doc {
+		return nil
+	}
+	return h.origins
+}
+
+//boundary reports whether processors were called at position of node
+func boundary(nodes []ast.Node, index, position int) bool {
+	span := spanOf(nodes[index])
+	return span.Start == position && (index == 0 || spanOf(nodes[index-1]) != span)
+}
+
+func isText(node ast.Node) bool {
+	_, ok := node.(*ast.Text)
+	return ok
+}
+
+func hasSpans(nodes []ast.Node) bool {
+	for _, node := range nodes {
+		if _, ok := ast.SpanOf(node); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func spanOf(node ast.Node) ast.Span {
+	span, _ := ast.SpanOf(node)
+	return span
+}
@@ -0,0 +1,247 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+//keyword creates empty container for word
+func keyword(word string) parser.Processor {
+	return func(node ast.ParentNode, data []byte, parse func(ast.ParentNode, []byte) error) (int, error) {
+		if !bytes.HasPrefix(data, []byte(word)) {
+			return 0, nil
+		}
+		node.AppendNode(ast.NewContainer())
+		return len(word), nil
+	}
+}
+
+//spans lists spans of all nodes of tree in order of Inspect
+func spans(node ast.Node) []ast.Span {
+	var result []ast.Span
+	ast.Inspect(node, func(node ast.Node) bool {
+		span, _ := ast.SpanOf(node)
+		result = append(result, span)
+		return true
+	})
+	return result
+}
+
+func TestParser_Reparse(t *testing.T) {
+	p := parser.New(
+		processorQuote(parser.OnUnterminated(parser.UnterminatedText)),
+		processorDoubleQuote(parser.KeepDelimiters(), parser.OnUnterminated(parser.UnterminatedText)),
+	)
+	p.SetOptions(parser.Incremental(0))
+	data := []byte(`a 'b' "c 'd' e" f 'g`)
+	t.Run(`equals parse`, func(t *testing.T) {
+		for start := 0; start <= len(data); start++ {
+			for end := start; end <= len(data); end++ {
+				for _, replacement := range []string{``, `'`, `"`, `x`, `' "`} {
+					input := append(append(append([]byte(nil), data[:start]...), replacement...), data[end:]...)
+					old, err := p.Parse(data)
+					if !assert.NoError(t, err) {
+						return
+					}
+					node, err := p.Reparse(old, input, parser.Edit{Start: start, OldEnd: end, NewEnd: start + len(replacement)})
+					if !assert.NoError(t, err) {
+						return
+					}
+					expected, _ := p.Parse(input)
+					if !assert.True(t, ast.Equal(expected, node), `%q: %v`, input, ast.Diff(expected, node)) {
+						return
+					}
+				}
+			}
+		}
+	})
+	t.Run(`reuse`, func(t *testing.T) {
+		old, _ := p.Parse(data)
+		children := append([]ast.Node(nil), old.(*ast.Document).Children...)
+		input := []byte(`a 'b' "c 'd' e" xf 'g`)
+		node, err := p.Reparse(old, input, parser.Edit{Start: 16, OldEnd: 16, NewEnd: 17})
+		if !assert.NoError(t, err) {
+			return
+		}
+		reparsed := node.(*ast.Document).Children
+		if !assert.Len(t, reparsed, 5) {
+			return
+		}
+		assert.True(t, children[1] == reparsed[1])
+		assert.True(t, children[3] == reparsed[3])
+		assert.Equal(t, ast.Span{Start: 6, End: 15}, reparsed[3].(*quote).Span)
+		assert.Equal(t, ast.Span{Start: 15, End: 21}, reparsed[4].(*ast.Text).Span)
+	})
+	t.Run(`shift`, func(t *testing.T) {
+		old, _ := p.Parse(data)
+		children := append([]ast.Node(nil), old.(*ast.Document).Children...)
+		input := []byte(`a "c 'd' e" f 'g`)
+		node, err := p.Reparse(old, input, parser.Edit{Start: 2, OldEnd: 6, NewEnd: 2})
+		if !assert.NoError(t, err) {
+			return
+		}
+		reparsed := node.(*ast.Document).Children
+		if !assert.Len(t, reparsed, 3) {
+			return
+		}
+		assert.True(t, children[3] == reparsed[1])
+		inner := reparsed[1].(*quote)
+		assert.Equal(t, ast.Span{Start: 2, End: 11}, inner.Span)
+		assert.Equal(t, ast.Span{Start: 5, End: 8}, inner.Children[1].(*quote).Span)
+		assert.Equal(t, ast.Span{Start: 6, End: 7}, inner.Children[1].(*quote).Children[0].(*ast.Text).Span)
+	})
+	t.Run(`earlier processor`, func(t *testing.T) {
+		factory := func() ast.ParentNode {
+			return &quote{Container: ast.NewContainer()}
+		}
+		p := parser.New(
+			parser.ProcessorByStrings(`<`, `>`, factory, parser.OnUnterminated(parser.UnterminatedText)),
+			parser.ProcessorByStrings(`<<`, `!`, factory),
+		)
+		p.SetOptions(parser.Incremental(0))
+		old, err := p.Parse([]byte(`<<x! y`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		input := []byte(`<<x! y>`)
+		node, err := p.Reparse(old, input, parser.Edit{Start: 6, OldEnd: 6, NewEnd: 7})
+		if !assert.NoError(t, err) {
+			return
+		}
+		expected, _ := p.Parse(input)
+		assert.True(t, ast.Equal(expected, node), ast.Diff(expected, node))
+	})
+	t.Run(`not latest`, func(t *testing.T) {
+		old, _ := p.Parse(data)
+		//old is not the latest tree, so nodes before the edit are parsed again
+		_, _ = p.Parse(data)
+		input := []byte(`a 'b' "c 'd' e" xf 'g`)
+		node, err := p.Reparse(old, input, parser.Edit{Start: 16, OldEnd: 16, NewEnd: 17})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.True(t, old.(*ast.Document).Children[1] != node.(*ast.Document).Children[1])
+		expected, _ := p.Parse(input)
+		assert.True(t, ast.Equal(expected, node), ast.Diff(expected, node))
+	})
+	t.Run(`not incremental`, func(t *testing.T) {
+		p := parser.New(processorQuote(parser.OnUnterminated(parser.UnterminatedText)))
+		old, _ := p.Parse([]byte(`'a' x 'b'`))
+		children := append([]ast.Node(nil), old.(*ast.Document).Children...)
+		node, err := p.Reparse(old, []byte(`'a' xy 'b'`), parser.Edit{Start: 5, OldEnd: 5, NewEnd: 6})
+		if !assert.NoError(t, err) {
+			return
+		}
+		reparsed := node.(*ast.Document).Children
+		if !assert.Len(t, reparsed, 3) {
+			return
+		}
+		assert.True(t, children[0] != reparsed[0])
+		assert.True(t, children[2] == reparsed[2])
+		assert.Equal(t, ast.Span{Start: 7, End: 10}, reparsed[2].(*quote).Span)
+	})
+	t.Run(`max nodes`, func(t *testing.T) {
+		p := parser.New(processorQuote())
+		old, _ := p.Parse([]byte(`'a' 'b' c`))
+		p.SetOptions(parser.MaxNodes(5))
+		_, err := p.Reparse(old, []byte(`'a' 'b' cd`), parser.Edit{Start: 9, OldEnd: 9, NewEnd: 10})
+		assert.True(t, errors.Is(err, parser.ErrMaxNodes))
+	})
+	t.Run(`invalid edit`, func(t *testing.T) {
+		old, _ := p.Parse(data)
+		_, err := p.Reparse(old, data, parser.Edit{Start: 1, OldEnd: 2, NewEnd: 3})
+		assert.Equal(t, parser.ErrInvalidEdit, err)
+		_, err = p.Reparse(old, data, parser.Edit{Start: 2, OldEnd: 1, NewEnd: 1})
+		assert.Equal(t, parser.ErrInvalidEdit, err)
+	})
+	t.Run(`not document`, func(t *testing.T) {
+		node, err := p.Reparse(ast.NewContainer(), data, parser.Edit{})
+		if !assert.NoError(t, err) {
+			return
+		}
+		expected, _ := p.Parse(data)
+		assert.True(t, ast.Equal(expected, node))
+	})
+}
+
+func TestParser_Reparse_Random(t *testing.T) {
+	factory := func() ast.ParentNode {
+		return ast.NewContainer()
+	}
+	newParser := func(options ...parser.Option) parser.Parser {
+		p := parser.New(
+			keyword(`ab`),
+			keyword(`c`),
+			parser.ProcessorBalanced('(', ')', factory, parser.SkipQuoted('\''), parser.OnUnterminated(parser.UnterminatedText)),
+			processorQuote(parser.OnUnterminated(parser.UnterminatedText)),
+			processorDoubleQuote(parser.EscapeByDoubling(), parser.KeepDelimiters(), parser.OnUnterminated(parser.UnterminatedText)),
+		)
+		p.SetOptions(options...)
+		return p
+	}
+	const alphabet = `abc()'" }`
+	var random *rand.Rand
+	generate := func(length int) []byte {
+		result := make([]byte, length)
+		for index := range result {
+			result[index] = alphabet[random.Intn(len(alphabet))]
+		}
+		return result
+	}
+	//doubled quote is checked one byte past the closing quote
+	for name, p := range map[string]parser.Parser{`default`: newParser(), `incremental`: newParser(parser.Incremental(1))} {
+		t.Run(name, func(t *testing.T) {
+			random = rand.New(rand.NewSource(1))
+			for i := 0; i < 20000; i++ {
+				data := generate(random.Intn(16))
+				start := random.Intn(len(data) + 1)
+				end := start + random.Intn(len(data)-start+1)
+				replacement := generate(random.Intn(4))
+				input := append(append(append([]byte(nil), data[:start]...), replacement...), data[end:]...)
+				old, err := p.Parse(data)
+				if !assert.NoError(t, err) {
+					return
+				}
+				node, err := p.Reparse(old, input, parser.Edit{Start: start, OldEnd: end, NewEnd: start + len(replacement)})
+				if !assert.NoError(t, err) {
+					return
+				}
+				expected, _ := p.Parse(input)
+				if !assert.True(t, ast.Equal(expected, node), "%q -> %q: %v", data, input, ast.Diff(expected, node)) ||
+					!assert.Equal(t, spans(expected), spans(node), "%q -> %q", data, input) {
+					return
+				}
+			}
+		})
+	}
+}
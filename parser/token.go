@@ -0,0 +1,168 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser/lexer"
+)
+
+type (
+	//TokenProcessor works like Processor on tokens of input, it returns number of consumed tokens
+	TokenProcessor func(node ast.ParentNode, tokens []lexer.Token, parser func(ast.ParentNode, []lexer.Token) error) (int, error)
+	tokenizer      struct {
+		lexer      *lexer.Lexer
+		processors []TokenProcessor
+		//processor is returned by Tokenize, it is reported in errors of token processors
+		processor Processor
+	}
+	tokenStream struct {
+		ast.ParentNode
+		*tokenizer
+		handled bool
+	}
+)
+
+//Tokenize converts token processors to processor which splits data by lexer and consumes the whole data.
+//Nodes created by token processors get span of consumed tokens, tokens which no processor consumes and input skipped by lexer
+//between processor calls are appended as text nodes, so rendered tree has the whole data.
+//Nested parser callback parses input from the first to the last token with all processors, so Tokenize is run again for it.
+//ParseReader buffers the whole input before tokens of the top level are processed.
+func Tokenize(l *lexer.Lexer, processors ...TokenProcessor) Processor {
+	t := &tokenizer{lexer: l, processors: processors}
+	t.processor = func(node ast.ParentNode, data []byte, parser func(ast.ParentNode, []byte) error) (int, error) {
+		stream := &tokenStream{ParentNode: node, tokenizer: t}
+		if err := parser(stream, data); err != nil || !stream.handled {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	return t.processor
+}
+
+//tokenize splits data into tokens and runs token processors until all tokens are consumed
+func (s *state) tokenize(t *tokenStream, data []byte) error {
+	t.handled = true
+	start := s.offset(data)
+	more := s.more(data)
+	tokens, n := t.lexer.Scan(nil, data, start, !more)
+	switch {
+	case n < len(data) && more:
+		return ErrIncomplete
+	case n < len(data):
+		return s.error(start+n, t.processor, fmt.Errorf("%w %q", lexer.ErrUnexpected, data[n]))
+	}
+	nested := func(node ast.ParentNode, tokens []lexer.Token) error {
+		return s.nested(node, tokenBytes(tokens))
+	}
+	//text is offset of input which is not consumed by token processors yet
+	text := start
+	for len(tokens) > 0 {
+		if err := s.done(tokens[0].Bytes); err != nil {
+			return err
+		}
+		index := len(t.GetChildren())
+		n, err := t.process(t.ParentNode, tokens, nested)
+		if err != nil {
+			err = s.error(tokens[0].Span.Start, t.processor, err)
+			if n, err = s.recoverTokens(t, index, tokens, err); err != nil {
+				return err
+			}
+		}
+		if n == 0 {
+			tokens = tokens[1:]
+			continue
+		}
+		setSpan(ast.Span{Start: tokens[0].Span.Start, End: tokens[n-1].Span.End}, t.GetChildren()[index:]...)
+		if text < tokens[0].Span.Start {
+			t.InsertNode(index, s.text(text, data[text-start:tokens[0].Span.Start-start]))
+		}
+		text = tokens[n-1].Span.End
+		tokens = tokens[n:]
+	}
+	if text < start+len(data) {
+		t.AppendNode(s.text(text, data[text-start:]))
+	}
+	return nil
+}
+
+//process runs token processors until one of them consumes tokens
+func (t *tokenizer) process(node ast.ParentNode, tokens []lexer.Token, parser func(ast.ParentNode, []lexer.Token) error) (int, error) {
+	for _, processor := range t.processors {
+		if n, err := processor(node, tokens, parser); err != nil {
+			return 0, err
+		} else if n != 0 {
+			if n > len(tokens) {
+				n = len(tokens)
+			}
+			return n, nil
+		}
+	}
+	return 0, nil
+}
+
+//recoverTokens replaces tokens up to the token equal to sync point with ast.Error node when Recover option is set
+func (s *state) recoverTokens(t *tokenStream, index int, tokens []lexer.Token, err error) (int, error) {
+	var parseError *Error
+	if !s.recovery || !errors.As(err, &parseError) || errors.Is(err, ErrIncomplete) {
+		return 0, err
+	}
+	n := 1
+	for n < len(tokens) && !s.isSyncPoint(tokens[n].Bytes) {
+		n++
+	}
+	for length := len(t.GetChildren()); length > index; length-- {
+		t.DeleteNode(length - 1)
+	}
+	s.errors.Add(parseError)
+	content := tokenBytes(tokens[:n])
+	content = content[:len(content):len(content)]
+	if s.copyText {
+		content = append([]byte(nil), content...)
+	}
+	t.AppendNode(ast.NewError(parseError, content...))
+	return n, nil
+}
+
+func (s *state) isSyncPoint(data []byte) bool {
+	for _, point := range s.syncPoints {
+		if string(point) == string(data) {
+			return true
+		}
+	}
+	return len(s.syncPoints) == 0
+}
+
+//tokenBytes returns input from the first to the last token
+func tokenBytes(tokens []lexer.Token) []byte {
+	if len(tokens) == 0 {
+		return nil
+	}
+	first, last := tokens[0], tokens[len(tokens)-1]
+	return first.Bytes[:last.Span.End-first.Span.Start]
+}
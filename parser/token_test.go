@@ -0,0 +1,155 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/biodebox/yaastr/parser/lexer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"testing/iotest"
+)
+
+const (
+	tokenIdentifier lexer.Kind = iota + 1
+	tokenNumber
+	tokenPunct
+)
+
+var errArgument = errors.New("bad argument")
+
+func tokenLexer() *lexer.Lexer {
+	return lexer.New(
+		lexer.Skip(lexer.Space()),
+		lexer.Define(tokenIdentifier, lexer.Identifier()),
+		lexer.Define(tokenNumber, lexer.Number()),
+		lexer.Define(tokenPunct, lexer.Literal("(")),
+		lexer.Define(tokenPunct, lexer.Literal(")")),
+		lexer.Define(tokenPunct, lexer.Literal(";")),
+		lexer.Define(tokenPunct, lexer.Literal("!")),
+	)
+}
+
+//tokenCall parses calls like f(1 g(2)) into containers, arguments are parsed by nested callback
+func tokenCall() parser.TokenProcessor {
+	return func(node ast.ParentNode, tokens []lexer.Token, parse func(ast.ParentNode, []lexer.Token) error) (int, error) {
+		if len(tokens) < 2 || tokens[0].Kind != tokenIdentifier || string(tokens[1].Bytes) != "(" {
+			return 0, nil
+		}
+		depth := 0
+		for index, token := range tokens[1:] {
+			switch string(token.Bytes) {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			case "!":
+				return 0, errArgument
+			}
+			if depth == 0 {
+				call := ast.NewContainer()
+				node.AppendNode(call)
+				return index + 2, parse(call, tokens[2:index+1])
+			}
+		}
+		return 0, nil
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	data := []byte("f(1 g( x ))\n2")
+	p := parser.New(parser.Tokenize(tokenLexer(), tokenCall()))
+	node, err := p.Parse(data)
+	if !assert.NoError(t, err) {
+		return
+	}
+	doc := document(len(data))
+	inner := span(ast.NewContainer(), 4, 10).(*ast.Container)
+	inner.AppendNode(text(`x`, 7))
+	outer := span(ast.NewContainer(), 0, 11).(*ast.Container)
+	outer.AppendNode(text(`1 `, 2), inner)
+	doc.AppendNode(outer, text("\n2", 11))
+	assert.True(t, ast.Equal(doc, node), ast.Diff(doc, node))
+	t.Run(`reader`, func(t *testing.T) {
+		actual, err := p.ParseReader(iotest.OneByteReader(bytes.NewReader(data)))
+		if assert.NoError(t, err) {
+			assert.True(t, ast.Equal(doc, actual), ast.Diff(doc, actual))
+		}
+	})
+	t.Run(`skipped input`, func(t *testing.T) {
+		data := []byte("  a f(1)  b\n")
+		node, err := p.Parse(data)
+		if !assert.NoError(t, err) {
+			return
+		}
+		call := span(ast.NewContainer(), 4, 8).(*ast.Container)
+		call.AppendNode(text(`1`, 6))
+		doc := document(len(data))
+		doc.AppendNode(text(`  a `, 0), call, text("  b\n", 8))
+		assert.True(t, ast.Equal(doc, node), ast.Diff(doc, node))
+		node, err = parser.New(parser.Tokenize(tokenLexer())).Parse(data)
+		if !assert.NoError(t, err) {
+			return
+		}
+		rendered := &bytes.Buffer{}
+		if assert.NoError(t, ast.Render(rendered, node)) {
+			assert.Equal(t, string(data), rendered.String())
+		}
+	})
+	t.Run(`unexpected input`, func(t *testing.T) {
+		_, err := p.Parse([]byte("f(1 ?)"))
+		var parseError *parser.Error
+		if assert.True(t, errors.As(err, &parseError)) {
+			assert.Equal(t, 4, parseError.Offset)
+			assert.True(t, errors.Is(err, lexer.ErrUnexpected))
+			assert.EqualError(t, err, `1:5: unexpected input '?'`)
+		}
+	})
+	t.Run(`processor error`, func(t *testing.T) {
+		_, err := p.Parse([]byte("a f(!)"))
+		var parseError *parser.Error
+		if assert.True(t, errors.As(err, &parseError)) {
+			assert.Equal(t, 2, parseError.Offset)
+			assert.Equal(t, errArgument, parseError.Err)
+		}
+	})
+	t.Run(`recover`, func(t *testing.T) {
+		p := parser.New(parser.Tokenize(tokenLexer(), tokenCall()))
+		p.SetOptions(parser.Recover(";"))
+		data := []byte("f(!) x; y")
+		node, err := p.Parse(data)
+		var list parser.ErrorList
+		if !assert.True(t, errors.As(err, &list)) || !assert.Len(t, list, 1) {
+			return
+		}
+		doc := document(len(data))
+		doc.AppendNode(errorNode(list[0], `f(!) x`, 0), text(`; y`, 6))
+		assert.True(t, ast.Equal(doc, node), ast.Diff(doc, node))
+	})
+}
@@ -27,22 +27,340 @@ package parser
 
 import (
 	"bytes"
+	"fmt"
 	"github.com/biodebox/yaastr/ast"
+	"unicode/utf8"
 )
 
-func ProcessorByRune(opening, ending rune, nodeFactory func() ast.ParentNode) Processor {
+type (
+	Unterminated    int
+	DelimiterOption func(*delimiter)
+	delimiter       struct {
+		unterminated Unterminated
+		nested       bool
+		escape       []byte
+		quotes       [][]byte
+		doubling     bool
+		verbatim     bool
+		unescape     bool
+		keep         bool
+		inMode       bool
+		mode         string
+	}
+	UnterminatedError struct {
+		Opening string
+	}
+	//protected is parsed like wrapped node, but ranges of data are added to text without running processors
+	protected struct {
+		ast.ParentNode
+		ranges [][2]int
+	}
+)
+
+const (
+	//UnterminatedFail stops parsing with *UnterminatedError
+	UnterminatedFail Unterminated = iota
+	//UnterminatedText treats opening delimiter as plain text
+	UnterminatedText
+	//UnterminatedClose closes node at the end of input
+	UnterminatedClose
+)
+
+//OnUnterminated sets behavior for opening delimiter without ending one
+func OnUnterminated(policy Unterminated) DelimiterOption {
+	return func(d *delimiter) {
+		d.unterminated = policy
+	}
+}
+
+//Nested skips nested pairs of opening and ending delimiters
+func Nested() DelimiterOption {
+	return func(d *delimiter) {
+		d.nested = true
+	}
+}
+
+//SkipEscaped ignores delimiters and quotes right after escape rune
+func SkipEscaped(escape rune) DelimiterOption {
+	return func(d *delimiter) {
+		d.escape = []byte(string(escape))
+	}
+}
+
+//EscapeByDoubling treats doubled ending delimiter as escaped one
+func EscapeByDoubling() DelimiterOption {
+	return func(d *delimiter) {
+		d.doubling = true
+	}
+}
+
+//Verbatim stores content as single text node with escapes kept, without running processors
+func Verbatim() DelimiterOption {
+	return func(d *delimiter) {
+		d.verbatim = true
+	}
+}
+
+//Unescape works like Verbatim but removes escape runes and doubled delimiters from content
+func Unescape() DelimiterOption {
+	return func(d *delimiter) {
+		d.unescape = true
+	}
+}
+
+//KeepDelimiters attaches matched delimiters to nodes implementing ast.Delimited so tree can be rendered back
+func KeepDelimiters() DelimiterOption {
+	return func(d *delimiter) {
+		d.keep = true
+	}
+}
+
+//InMode parses content by processors of named mode, see PushMode
+func InMode(name string) DelimiterOption {
+	return func(d *delimiter) {
+		d.inMode = true
+		d.mode = name
+	}
+}
+
+//SkipQuoted ignores delimiters inside regions enclosed by the same quote rune
+func SkipQuoted(quotes ...rune) DelimiterOption {
+	return func(d *delimiter) {
+		for _, quote := range quotes {
+			d.quotes = append(d.quotes, []byte(string(quote)))
+		}
+	}
+}
+
+//Error position of opening delimiter is reported by wrapping *Error
+func (e *UnterminatedError) Error() string {
+	return fmt.Sprintf("unterminated %q", e.Opening)
+}
+
+//ProcessorByRune creates node for data between opening and ending runes, runes are matched as UTF-8 sequences
+func ProcessorByRune(opening, ending rune, nodeFactory func() ast.ParentNode, options ...DelimiterOption) Processor {
+	return newDelimiter(options).processor([]byte(string(opening)), []byte(string(ending)), nodeFactory)
+}
+
+//ProcessorBalanced works like ProcessorByRune but skips nested pairs of opening and ending runes
+func ProcessorBalanced(opening, ending rune, nodeFactory func() ast.ParentNode, options ...DelimiterOption) Processor {
+	config := newDelimiter(options)
+	config.nested = true
+	return config.processor([]byte(string(opening)), []byte(string(ending)), nodeFactory)
+}
+
+//ProcessorByDelimiters creates node for data between opening and ending byte sequences
+func ProcessorByDelimiters(opening, ending []byte, nodeFactory func() ast.ParentNode, options ...DelimiterOption) Processor {
+	if len(opening) == 0 || len(ending) == 0 {
+		panic("yaastr: empty delimiter")
+	}
+	return newDelimiter(options).processor(opening, ending, nodeFactory)
+}
+
+//ProcessorByStrings is ProcessorByDelimiters for string delimiters
+func ProcessorByStrings(opening, ending string, nodeFactory func() ast.ParentNode, options ...DelimiterOption) Processor {
+	return ProcessorByDelimiters([]byte(opening), []byte(ending), nodeFactory, options...)
+}
+
+func newDelimiter(options []DelimiterOption) *delimiter {
+	config := &delimiter{}
+	for _, option := range options {
+		option(config)
+	}
+	return config
+}
+
+func (d *delimiter) processor(open, close []byte, nodeFactory func() ast.ParentNode) Processor {
 	return func(parentNode ast.ParentNode, data []byte, parser func(ast.ParentNode, []byte) error) (int, error) {
-		if data[0] == byte(opening) {
-			end := bytes.IndexRune(data[1:], ending) + 1
-			data = data[1:end]
-			node := nodeFactory()
-			parentNode.AppendNode(node)
-			if err := parser(node, data); err != nil {
+		if !bytes.HasPrefix(data, open) {
+			if len(data) < len(open) && bytes.HasPrefix(open, data) && More(parser, data) {
+				return 0, ErrIncomplete
+			}
+			return 0, nil
+		}
+		start := len(open)
+		end := d.find(data[start:], open, close)
+		next := start + end + len(close)
+		if end < 0 {
+			if More(parser, data) {
+				return 0, ErrIncomplete
+			}
+			switch d.unterminated {
+			case UnterminatedText:
+				return 0, nil
+			case UnterminatedClose:
+				end, next = len(data)-start, len(data)
+			default:
+				return 0, &UnterminatedError{Opening: string(open)}
+			}
+		}
+		node := nodeFactory()
+		if delimited, ok := node.(ast.Delimited); ok && d.keep {
+			delimited.SetDelimiters(data[:start:start], data[start+end:next:next])
+		}
+		parentNode.AppendNode(node)
+		if !d.verbatim && !d.unescape {
+			if err := d.parseContent(node, data[start:start+end], open, close, parser); err != nil {
 				return 0, err
 			}
-			return end + 1, nil
+			return next, nil
+		}
+		if err := parser(Literal(node), data[start:start+end]); err != nil {
+			return 0, err
+		}
+		if d.unescape {
+			for _, child := range node.GetChildren() {
+				if text, ok := child.(*ast.Text); ok {
+					text.Content = d.decode(text.Content, close)
+				}
+			}
+		}
+		return next, nil
+	}
+}
+
+//parseContent runs nested parse for data, escaped runes, doubled delimiters and quoted regions outside of nested pairs
+//are added to text without running processors, so they never act as delimiters again.
+//Regions inside nested pairs are left to the processor call which matches the pair. Content is parsed in mode of InMode option.
+func (d *delimiter) parseContent(node ast.ParentNode, data, open, close []byte, parser func(ast.ParentNode, []byte) error) error {
+	var ranges [][2]int
+	depth := 0
+	for index := 0; index < len(data); {
+		length := 0
+		quote := d.quote(data[index:])
+		switch {
+		case d.escaped(data[index:]):
+			length = d.skipEscaped(data[index:])
+		case quote != nil:
+			length = len(data) - index
+			if end := d.findQuote(data[index+len(quote):], quote); end >= 0 {
+				length = len(quote) + end + len(quote)
+			}
+		case d.doubled(data[index:], close):
+			length = 2 * len(close)
+		case depth > 0 && bytes.HasPrefix(data[index:], close):
+			depth--
+			index += len(close)
+			continue
+		case d.nested && bytes.HasPrefix(data[index:], open):
+			depth++
+			index += len(open)
+			continue
+		default:
+			_, size := utf8.DecodeRune(data[index:])
+			index += size
+			continue
+		}
+		if depth == 0 {
+			ranges = append(ranges, [2]int{index, index + length})
+		}
+		index += length
+	}
+	content := node
+	if len(ranges) > 0 {
+		content = &protected{ParentNode: node, ranges: ranges}
+	}
+	if d.inMode {
+		content = PushMode(d.mode, content)
+	}
+	return parser(content, data)
+}
+
+//find returns index of ending delimiter or -1
+func (d *delimiter) find(data, open, close []byte) int {
+	depth := 0
+	for index := 0; index < len(data); {
+		if d.escaped(data[index:]) {
+			index += d.skipEscaped(data[index:])
+			continue
+		}
+		if quote := d.quote(data[index:]); quote != nil {
+			end := d.findQuote(data[index+len(quote):], quote)
+			if end < 0 {
+				return -1
+			}
+			index += len(quote) + end + len(quote)
+			continue
+		}
+		if d.doubled(data[index:], close) {
+			index += 2 * len(close)
+			continue
+		}
+		if bytes.HasPrefix(data[index:], close) {
+			if depth == 0 {
+				return index
+			}
+			depth--
+			index += len(close)
+			continue
+		}
+		if d.nested && bytes.HasPrefix(data[index:], open) {
+			depth++
+			index += len(open)
+			continue
+		}
+		_, size := utf8.DecodeRune(data[index:])
+		index += size
+	}
+	return -1
+}
+
+//findQuote returns index of closing quote or -1
+func (d *delimiter) findQuote(data, quote []byte) int {
+	for index := 0; index < len(data); {
+		if d.escaped(data[index:]) {
+			index += d.skipEscaped(data[index:])
+			continue
+		}
+		if bytes.HasPrefix(data[index:], quote) {
+			return index
 		}
+		_, size := utf8.DecodeRune(data[index:])
+		index += size
+	}
+	return -1
+}
+
+//decode removes escape runes and doubled delimiters
+func (d *delimiter) decode(data, close []byte) []byte {
+	content := make([]byte, 0, len(data))
+	for index := 0; index < len(data); {
+		if d.escaped(data[index:]) {
+			size := d.skipEscaped(data[index:])
+			content = append(content, data[index+len(d.escape):index+size]...)
+			index += size
+			continue
+		}
+		if d.doubled(data[index:], close) {
+			content = append(content, close...)
+			index += 2 * len(close)
+			continue
+		}
+		content = append(content, data[index])
+		index++
+	}
+	return content
+}
 
-		return 0, nil
+func (d *delimiter) doubled(data, close []byte) bool {
+	return d.doubling && bytes.HasPrefix(data, close) && bytes.HasPrefix(data[len(close):], close)
+}
+
+func (d *delimiter) escaped(data []byte) bool {
+	return len(d.escape) > 0 && bytes.HasPrefix(data, d.escape)
+}
+
+//skipEscaped returns length of escape rune with escaped rune
+func (d *delimiter) skipEscaped(data []byte) int {
+	_, size := utf8.DecodeRune(data[len(d.escape):])
+	return len(d.escape) + size
+}
+
+func (d *delimiter) quote(data []byte) []byte {
+	for _, quote := range d.quotes {
+		if bytes.HasPrefix(data, quote) {
+			return quote
+		}
 	}
-}
\ No newline at end of file
+	return nil
+}
@@ -0,0 +1,499 @@
+// Copyright © 2019. Vladislav Karpenko <recyger@gmail.com>
+//
+// All rights reserved.
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package parser_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/biodebox/yaastr/ast"
+	"github.com/biodebox/yaastr/parser"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestProcessorByRune(t *testing.T) {
+	t.Run(`unterminated fail`, func(t *testing.T) {
+		_, err := parser.New(processorQuote()).Parse([]byte(`text 'quote`))
+		if !assert.Error(t, err) {
+			return
+		}
+		var unterminated *parser.UnterminatedError
+		if !assert.True(t, errors.As(err, &unterminated)) {
+			return
+		}
+		assert.Equal(t, `'`, unterminated.Opening)
+		var parseError *parser.Error
+		if !assert.True(t, errors.As(err, &parseError)) {
+			return
+		}
+		assert.Equal(t, 5, parseError.Offset)
+		assert.Equal(t, `1:6: unterminated "'"`, err.Error())
+	})
+	t.Run(`unterminated text`, func(t *testing.T) {
+		node, err := parser.New(processorQuote(parser.OnUnterminated(parser.UnterminatedText))).Parse([]byte(`text 'quote`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(11)
+		doc.AppendNode(text(`text 'quote`, 0))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`unterminated close`, func(t *testing.T) {
+		node, err := parser.New(processorQuote(parser.OnUnterminated(parser.UnterminatedClose))).Parse([]byte(`text 'quote`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(11)
+		doc.AppendNode(text(`text `, 0))
+		doc.AppendNode(span(&quote{
+			Container: ast.NewContainer(text(`quote`, 6)),
+		}, 5, 11))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`unterminated close empty`, func(t *testing.T) {
+		node, err := parser.New(processorQuote(parser.OnUnterminated(parser.UnterminatedClose))).Parse([]byte(`'`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(1)
+		doc.AppendNode(span(&quote{
+			Container: ast.NewContainer(),
+		}, 0, 1))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`unterminated nested`, func(t *testing.T) {
+		node, err := parser.New(
+			processorQuote(parser.OnUnterminated(parser.UnterminatedClose)),
+			processorDoubleQuote(parser.OnUnterminated(parser.UnterminatedText)),
+		).Parse([]byte(`'a "b`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(5)
+		doc.AppendNode(span(&quote{
+			Container: ast.NewContainer(text(`a "b`, 1)),
+		}, 0, 5))
+		assert.Equal(t, doc, node)
+	})
+}
+
+func TestProcessorByRune_UTF8(t *testing.T) {
+	factory := func() ast.ParentNode {
+		return &quote{Container: ast.NewContainer()}
+	}
+	t.Run(`cyrillic`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorByRune('Ж', 'Щ', factory)).Parse([]byte(`а ЖбЩ в`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(12)
+		doc.AppendNode(text(`а `, 0))
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(text(`б`, 5))}, 3, 9))
+		doc.AppendNode(text(` в`, 9))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`guillemets`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorByRune('«', '»', factory)).Parse([]byte(`«цитата»`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(16)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(text(`цитата`, 2))}, 0, 16))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`cjk`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorByRune('「', '」', factory)).Parse([]byte(`「引用」`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(12)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(text(`引用`, 3))}, 0, 12))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`emoji`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorByRune('👉', '👈', factory)).Parse([]byte(`👉hi👈!`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(11)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(text(`hi`, 4))}, 0, 10))
+		doc.AppendNode(text(`!`, 10))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`same last byte`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorByRune('«', '»', factory)).Parse([]byte(`ī«a»`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(7)
+		doc.AppendNode(text("ī", 0))
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(text(`a`, 4))}, 2, 7))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`unterminated`, func(t *testing.T) {
+		_, err := parser.New(parser.ProcessorByRune('«', '»', factory)).Parse([]byte(`«a`))
+		assert.EqualError(t, err, `1:1: unterminated "«"`)
+	})
+}
+
+func TestProcessorBalanced(t *testing.T) {
+	factory := func() ast.ParentNode {
+		return &quote{Container: ast.NewContainer()}
+	}
+	t.Run(`nested`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorBalanced('(', ')', factory)).Parse([]byte(`(a (b) c) d`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(11)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(
+			text(`a `, 1),
+			span(&quote{Container: ast.NewContainer(text(`b`, 4))}, 3, 6),
+			text(` c`, 6),
+		)}, 0, 9))
+		doc.AppendNode(text(` d`, 9))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`mixed brackets`, func(t *testing.T) {
+		node, err := parser.New(
+			parser.ProcessorBalanced('[', ']', factory),
+			parser.ProcessorBalanced('{', '}', factory),
+		).Parse([]byte(`[{[]}]`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(6)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(
+			span(&quote{Container: ast.NewContainer(
+				span(&quote{Container: ast.NewContainer()}, 2, 4),
+			)}, 1, 5),
+		)}, 0, 6))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`by rune is not nested`, func(t *testing.T) {
+		_, err := parser.New(parser.ProcessorByRune('(', ')', factory)).Parse([]byte(`(a (b) c)`))
+		assert.EqualError(t, err, `1:4: unterminated "("`)
+	})
+	t.Run(`unterminated`, func(t *testing.T) {
+		_, err := parser.New(parser.ProcessorBalanced('(', ')', factory)).Parse([]byte(`x (a (b) c`))
+		assert.EqualError(t, err, `1:3: unterminated "("`)
+	})
+	t.Run(`escaped`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorBalanced('(', ')', factory, parser.SkipEscaped('\\'))).Parse([]byte(`(a\)b)`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(6)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(text(`a\)b`, 1))}, 0, 6))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`quoted`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorBalanced('(', ')', factory, parser.SkipQuoted('"', '\''))).Parse([]byte(`(")'" ')')`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(10)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(text(`")'" ')'`, 1))}, 0, 10))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`unterminated quote`, func(t *testing.T) {
+		_, err := parser.New(parser.ProcessorBalanced('(', ')', factory, parser.SkipQuoted('"'))).Parse([]byte(`(")`))
+		assert.EqualError(t, err, `1:1: unterminated "("`)
+	})
+	t.Run(`escaped opener`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorBalanced('(', ')', factory, parser.SkipEscaped('\\'))).Parse([]byte(`(a\(b (c\)) d)`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(14)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(
+			text(`a\(b `, 1),
+			span(&quote{Container: ast.NewContainer(text(`c\)`, 7))}, 6, 11),
+			text(` d`, 11),
+		)}, 0, 14))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`quoted opener`, func(t *testing.T) {
+		node, err := parser.New(
+			parser.ProcessorBalanced('(', ')', factory, parser.SkipQuoted('"')),
+			processorQuote(),
+		).Parse([]byte(`(a "(" 'b' ("("))`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(17)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(
+			text(`a "(" `, 1),
+			span(&quote{Container: ast.NewContainer(text(`b`, 8))}, 7, 10),
+			text(` `, 10),
+			span(&quote{Container: ast.NewContainer(text(`"("`, 12))}, 11, 16),
+		)}, 0, 17))
+		assert.Equal(t, doc, node)
+	})
+}
+
+func TestProcessorByDelimiters(t *testing.T) {
+	factory := func() ast.ParentNode {
+		return &quote{Container: ast.NewContainer()}
+	}
+	t.Run(`template`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorByStrings(`{{`, `}}`, factory)).Parse([]byte(`a {{ b }} c`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(11)
+		doc.AppendNode(text(`a `, 0))
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(text(` b `, 4))}, 2, 9))
+		doc.AppendNode(text(` c`, 9))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`comment`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorByDelimiters([]byte(`<!--`), []byte(`-->`), factory)).Parse([]byte(`<!-- a -- b -->`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(15)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(text(` a -- b `, 4))}, 0, 15))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`nested`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorByStrings(`/*`, `*/`, factory, parser.Nested())).Parse([]byte(`/*a/*b*/*/`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(10)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(
+			text(`a`, 2),
+			span(&quote{Container: ast.NewContainer(text(`b`, 5))}, 3, 8),
+		)}, 0, 10))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`unterminated fail`, func(t *testing.T) {
+		_, err := parser.New(parser.ProcessorByStrings(`{{`, `}}`, factory)).Parse([]byte(`a {{ b }`))
+		assert.EqualError(t, err, `1:3: unterminated "{{"`)
+	})
+	t.Run(`unterminated text`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorByStrings(`{{`, `}}`, factory, parser.OnUnterminated(parser.UnterminatedText))).Parse([]byte(`{{ b }`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(6)
+		doc.AppendNode(text(`{{ b }`, 0))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`unterminated close`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorByStrings(`{{`, `}}`, factory, parser.OnUnterminated(parser.UnterminatedClose))).Parse([]byte(`{{ b }`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(6)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(text(` b }`, 2))}, 0, 6))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`empty delimiter`, func(t *testing.T) {
+		assert.Panics(t, func() {
+			parser.ProcessorByStrings(``, `}}`, factory)
+		})
+	})
+}
+
+func TestProcessorByRune_Escape(t *testing.T) {
+	t.Run(`verbatim escape`, func(t *testing.T) {
+		node, err := parser.New(processorQuote(parser.SkipEscaped('\\'), parser.Verbatim())).Parse([]byte(`'it\'s' x`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(9)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(text(`it\'s`, 1))}, 0, 7))
+		doc.AppendNode(text(` x`, 7))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`unescape escape`, func(t *testing.T) {
+		node, err := parser.New(processorQuote(parser.SkipEscaped('\\'), parser.Unescape())).Parse([]byte(`'it\'s \\'`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(10)
+		decoded := span(ast.NewText([]byte(`it's \`)...), 1, 9)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(decoded)}, 0, 10))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`verbatim doubling`, func(t *testing.T) {
+		node, err := parser.New(processorQuote(parser.EscapeByDoubling(), parser.Verbatim())).Parse([]byte(`'it''s'`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(7)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(text(`it''s`, 1))}, 0, 7))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`unescape doubling`, func(t *testing.T) {
+		node, err := parser.New(processorQuote(parser.EscapeByDoubling(), parser.Unescape())).Parse([]byte(`'it''s''''' x`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(13)
+		decoded := span(ast.NewText([]byte(`it's''`)...), 1, 10)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(decoded)}, 0, 11))
+		doc.AppendNode(text(` x`, 11))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`unicode escape`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorByRune('«', '»', func() ast.ParentNode {
+			return &quote{Container: ast.NewContainer()}
+		}, parser.SkipEscaped('¦'), parser.Unescape())).Parse([]byte(`«a¦»b»`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(10)
+		decoded := span(ast.NewText([]byte(`a»b`)...), 2, 8)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(decoded)}, 0, 10))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`parsed escape`, func(t *testing.T) {
+		node, err := parser.New(
+			processorQuote(parser.SkipEscaped('\\')),
+			processorDoubleQuote(),
+		).Parse([]byte(`'it\'s "a" \\' x`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(16)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(
+			text(`it\'s `, 1),
+			span(&quote{Container: ast.NewContainer(text(`a`, 8)), double: true}, 7, 10),
+			text(` \\`, 10),
+		)}, 0, 14))
+		doc.AppendNode(text(` x`, 14))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`nested escaped quote`, func(t *testing.T) {
+		node, err := parser.New(
+			processorQuote(parser.SkipEscaped('\\')),
+			processorDoubleQuote(parser.SkipEscaped('\\')),
+		).Parse([]byte(`'x "a\"b" y'`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(12)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(
+			text(`x `, 1),
+			span(&quote{Container: ast.NewContainer(text(`a\"b`, 4)), double: true}, 3, 9),
+			text(` y`, 9),
+		)}, 0, 12))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`parsed doubling`, func(t *testing.T) {
+		node, err := parser.New(processorQuote(parser.EscapeByDoubling())).Parse([]byte(`'it''s'''`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(9)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(text(`it''s''`, 1))}, 0, 9))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`verbatim skips processors`, func(t *testing.T) {
+		node, err := parser.New(
+			processorQuote(parser.Verbatim()),
+			processorDoubleQuote(),
+		).Parse([]byte(`'"a"'`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(5)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer(text(`"a"`, 1))}, 0, 5))
+		assert.Equal(t, doc, node)
+	})
+	t.Run(`empty`, func(t *testing.T) {
+		node, err := parser.New(processorQuote(parser.Unescape())).Parse([]byte(`''`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		doc := document(2)
+		doc.AppendNode(span(&quote{Container: ast.NewContainer()}, 0, 2))
+		assert.Equal(t, doc, node)
+	})
+}
+
+func TestKeepDelimiters(t *testing.T) {
+	factory := func() ast.ParentNode {
+		return &quote{Container: ast.NewContainer()}
+	}
+	for name, test := range map[string]struct {
+		input      string
+		processors []parser.Processor
+	}{
+		`quotes`: {
+			input:      `a 'b "c" d' e`,
+			processors: []parser.Processor{processorQuote(parser.KeepDelimiters()), processorDoubleQuote(parser.KeepDelimiters())},
+		},
+		`balanced`: {
+			input:      `«x «y» z» {{ w }}`,
+			processors: []parser.Processor{parser.ProcessorBalanced('«', '»', factory, parser.KeepDelimiters()), parser.ProcessorByStrings(`{{`, `}}`, factory, parser.KeepDelimiters())},
+		},
+		`escaped`: {
+			input:      `'it\'s'`,
+			processors: []parser.Processor{processorQuote(parser.KeepDelimiters(), parser.SkipEscaped('\\'), parser.Verbatim())},
+		},
+		`unterminated`: {
+			input:      `a 'b`,
+			processors: []parser.Processor{processorQuote(parser.KeepDelimiters(), parser.OnUnterminated(parser.UnterminatedClose))},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			node, err := parser.New(test.processors...).Parse([]byte(test.input))
+			if !assert.NoError(t, err) {
+				return
+			}
+			var rendered bytes.Buffer
+			if !assert.NoError(t, ast.Render(&rendered, node)) {
+				return
+			}
+			assert.Equal(t, test.input, rendered.String())
+		})
+	}
+	t.Run(`attached`, func(t *testing.T) {
+		node, err := parser.New(parser.ProcessorByStrings(`<!--`, `-->`, factory, parser.KeepDelimiters())).Parse([]byte(`<!--x-->`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		opening, closing := node.(*ast.Document).Children[0].(*quote).GetDelimiters()
+		assert.Equal(t, []byte(`<!--`), opening)
+		assert.Equal(t, []byte(`-->`), closing)
+	})
+	t.Run(`edited`, func(t *testing.T) {
+		node, err := parser.New(processorQuote(parser.KeepDelimiters())).Parse([]byte(`a 'b'`))
+		if !assert.NoError(t, err) {
+			return
+		}
+		node.(*ast.Document).Children[1].(*quote).Children[0].(*ast.Text).Content = []byte(`c`)
+		var rendered bytes.Buffer
+		if !assert.NoError(t, ast.Render(&rendered, node)) {
+			return
+		}
+		assert.Equal(t, `a 'c'`, rendered.String())
+	})
+}